@@ -16,15 +16,68 @@ import (
 			""			giving an empty string will return the 1st non-loopback interface
 
 		Returns found IP4 address with trailing :PORT if one given
+
+	FindMyIP4Addrs(lead string) ([]string,error):
+		Same as FindMyIP4Addr, but returns all the matching IP4 addresses
+		(each with the trailing :PORT if one given), e.g. to listen on
+		every matching interface
+
+	FindMyIP4Interface(lead string) (*net.Interface,string,error):
+		Returns the interface FindMyIP4Addr would pick, and its IP4
+		address (any :PORT is ignored), e.g. to join a multicast group
+		on the interface
 */
 
+type (
+	ifAddr struct {
+		inf net.Interface
+		ip  string
+	}
+)
+
 func FindMyIP4Addr(lead string) (string, error) {
+	addrs, err := findIP4Addrs(lead, false)
+	if nil != err {
+		return "", err
+	}
+	return addrs[0], nil
+}
+
+func FindMyIP4Addrs(lead string) ([]string, error) {
+	return findIP4Addrs(lead, true)
+}
+
+func FindMyIP4Interface(lead string) (*net.Interface, string, error) {
+	ifas, err := findIP4(strings.Split(lead, ":")[0], false)
+	if nil != err {
+		return nil, "", err
+	}
+	return &ifas[0].inf, ifas[0].ip, nil
+}
+
+// ------------------------------------------------------------------------- //
+
+func findIP4Addrs(lead string, all bool) ([]string, error) {
 	tail := ""
 	i := strings.Split(lead, ":")
 	if len(i) == 2 {
 		tail = ":" + i[1]
 		lead = i[0]
 	}
+	ifas, err := findIP4(lead, all)
+	if nil != err {
+		return nil, err
+	}
+	found := []string{}
+	for _, ifa := range ifas {
+		found = append(found, ifa.ip+tail)
+	}
+	return found, nil
+}
+
+// Return the interfaces & their IP4 addresses matching lead (w/o any port)
+func findIP4(lead string, all bool) ([]ifAddr, error) {
+	found := []ifAddr{}
 	if len(lead) > 0 {
 		dbg.ChkTruX(lead[0] != '.') // no leading "."
 
@@ -45,13 +98,20 @@ func FindMyIP4Addr(lead string) (string, error) {
 				continue // skip IPv6 addresses
 			}
 			if "" != lead { // find interface that starts with "lead"
-				if strings.HasPrefix(a.String(), lead) {
-					return a.String()[:strings.Index(a.String(), "/")] + tail, nil
+				if !strings.HasPrefix(a.String(), lead) {
+					continue
 				}
-			} else if i.Name != "lo" { // return 1st non-loopback interface
-				return a.String()[:strings.Index(a.String(), "/")] + tail, nil
+			} else if i.Name == "lo" { // only non-loopback interfaces
+				continue
+			}
+			found = append(found, ifAddr{inf: i, ip: a.String()[:strings.Index(a.String(), "/")]})
+			if !all {
+				return found, nil
 			}
 		}
 	}
-	return "", Err_BadInterface
+	if 0 == len(found) {
+		return nil, Err_BadInterface
+	}
+	return found, nil
 }
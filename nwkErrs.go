@@ -2,6 +2,7 @@ package nwk
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"net"
 	"os"
@@ -28,8 +29,59 @@ var (
 	Err_AddressInUse      = errors.New("Address in use")
 	Err_IllegalParam      = errors.New("Illegal/missing param")
 	Err_BadInterface      = errors.New("Unknown interface")
+	Err_IdleTimeout       = errors.New("Idle timeout")
+	Err_MaxLifetime       = errors.New("Connection lifetime exceeded")
+	Err_HandlerPanic      = errors.New("Handler panic")
+	Err_Denied            = errors.New("Address denied")
+	Err_RateLimited       = errors.New("Rate limited")
+	Err_TooManyConns      = errors.New("Too many connections")
+	Err_NotSupported      = errors.New("Not supported")
+	Err_TooLarge          = errors.New("Data too large")
+	Err_ScanLimit         = errors.New("Scan limit reached")
+	Err_Unclassified      = errors.New("Unclassified error")
 )
 
+// errors recognized by ErrClass
+var errClasses = []error{
+	io.EOF,
+	Err_UnknownHost,
+	Err_AddrNotFound,
+	Err_ConnectionRefused,
+	Err_NoConnection,
+	Err_LostConnection,
+	Err_ClosedByUser,
+	Err_UserExit,
+	Err_Timeout,
+	Err_ResetByPeer,
+	Err_ClosedRemotely,
+	Err_EndOfFile,
+	Err_NoData,
+	Err_BadData,
+	Err_AddressInUse,
+	Err_IllegalParam,
+	Err_BadInterface,
+	Err_IdleTimeout,
+	Err_MaxLifetime,
+	Err_HandlerPanic,
+	Err_Denied,
+	Err_RateLimited,
+	Err_TooManyConns,
+	Err_NotSupported,
+	Err_TooLarge,
+	Err_ScanLimit,
+}
+
+type (
+	// Error from recovering a panic in a handler, errors.Is(err, Err_HandlerPanic)
+	PanicError struct {
+		Value interface{} // the value passed to panic
+		Stack []byte      // stack trace of the panicking GO ROUTINE
+	}
+)
+
+func (e *PanicError) Error() string { return fmt.Sprintf("%v: %v", Err_HandlerPanic, e.Value) }
+func (e *PanicError) Unwrap() error { return Err_HandlerPanic }
+
 func netErr(oerr, err error) error {
 	switch t := err.(type) {
 	case *net.OpError:
@@ -48,10 +100,19 @@ func netErr(oerr, err error) error {
 			return Err_ClosedRemotely
 		case syscall.EADDRINUSE:
 			return Err_AddressInUse
+		case syscall.EADDRNOTAVAIL:
+			return Err_AddrNotFound
+		case syscall.ENOPROTOOPT:
+			return Err_NotSupported
 		default:
 			dbg.Message("nwk.netErr - syscall.Errno: %03x  '%v'", int(t), oerr)
 		}
 	default:
+		for _, e := range errClasses {
+			if e == err { // already a nwk error, e.g. from a Control func
+				return e
+			}
+		}
 		if err.Error() == "use of closed network connection" { // better way to catch this?
 			return Err_NoConnection
 		}
@@ -74,3 +135,18 @@ func ChkNetErr(err error) error {
 	}
 	return err
 }
+
+// Returns the nwk error (or io.EOF) that err is or wraps,
+//	or Err_Unclassified for any other error, nil if err is nil
+func ErrClass(err error) error {
+	if nil == err {
+		return nil
+	}
+	err = ChkNetErr(err)
+	for _, e := range errClasses {
+		if errors.Is(err, e) {
+			return e
+		}
+	}
+	return Err_Unclassified
+}
@@ -25,3 +25,20 @@ func TestIPLoopPort(*testing.T) {
 	ip, _ := FindMyIP4Addr("127:1234")
 	dbg.Info("My IPAddr: %s", ip)
 }
+
+func TestIPAll(*testing.T) {
+	ips, _ := FindMyIP4Addrs("")
+	dbg.Info("My IPAddrs: %v", ips)
+}
+
+func TestIPAllPort(*testing.T) {
+	ips, _ := FindMyIP4Addrs(":1234")
+	dbg.Info("My IPAddrs: %v", ips)
+}
+
+func TestIPInterface(*testing.T) {
+	inf, ip, _ := FindMyIP4Interface("127")
+	if nil != inf {
+		dbg.Info("My loopback interface: %s %s", inf.Name, ip)
+	}
+}
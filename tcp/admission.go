@@ -0,0 +1,223 @@
+package tcp
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jayacarlson/nwk"
+)
+
+/*
+	Admission control for a Listener, checked by WaitOnConnection right
+		after a connection is accepted and before it is returned (or
+		passed to any ConnHandler)
+
+		A rejected connection is closed, counted in the Stats and sent as
+		an EvRejected event / status message, and WaitOnConnection then
+		continues waiting for the next connection:
+			Rej@<clientIP>(<reason>)
+				e.g. Rej@10.0.0.7:47556(Address denied)
+
+		Listener.Allow( cidr ... ) error:
+			Only allow connections from the given networks, either CIDR
+			("192.168.1.0/24") or single IPs ("127.0.0.1")
+			Can be called more than once to add to the list
+			Rejected with nwk.Err_Denied
+
+		Listener.Deny( cidr ... ) error:
+			Reject connections from the given networks, deny rules are
+			checked before any allow rules
+			Rejected with nwk.Err_Denied
+
+		Listener.SetRateLimit( perSecond float64, burst int ):
+			Limit new connections from each source IP using a token
+			bucket refilled at perSecond holding up to burst tokens,
+			0 perSecond is no limit
+			Rejected with nwk.Err_RateLimited
+
+		Listener.SetMaxPerIP( int ):
+			Limit the number of open connections from each source IP,
+			0 is no limit
+			Rejected with nwk.Err_TooManyConns
+*/
+
+type (
+	admission struct {
+		mu       sync.Mutex
+		allow    []*net.IPNet
+		deny     []*net.IPNet
+		rate     float64 // tokens per second, 0 is no limit
+		burst    float64
+		maxPerIP int
+		buckets  map[string]*bucket // rate limit buckets by IP
+		perIP    map[string]int     // open connections by IP
+	}
+
+	bucket struct {
+		tokens float64
+		last   time.Time
+	}
+)
+
+const maxBuckets = 1024 // most buckets kept, pruned (then the oldest evicted) past this
+
+func newAdmission() *admission {
+	return &admission{buckets: map[string]*bucket{}, perIP: map[string]int{}}
+}
+
+// ========================================================================= //
+
+// Only allow connections from the given networks
+func (l *Listener) Allow(cidrs ...string) error {
+	nets, err := parseCIDRs(cidrs)
+	if nil != err {
+		return err
+	}
+	l.adm.mu.Lock()
+	l.adm.allow = append(l.adm.allow, nets...)
+	l.adm.mu.Unlock()
+	return nil
+}
+
+// Reject connections from the given networks
+func (l *Listener) Deny(cidrs ...string) error {
+	nets, err := parseCIDRs(cidrs)
+	if nil != err {
+		return err
+	}
+	l.adm.mu.Lock()
+	l.adm.deny = append(l.adm.deny, nets...)
+	l.adm.mu.Unlock()
+	return nil
+}
+
+// Limit the rate of new connections from each source IP
+func (l *Listener) SetRateLimit(perSecond float64, burst int) {
+	if 1 > burst {
+		burst = 1
+	}
+	l.adm.mu.Lock()
+	l.adm.rate, l.adm.burst = perSecond, float64(burst)
+	l.adm.buckets = map[string]*bucket{}
+	l.adm.mu.Unlock()
+}
+
+// Limit the number of open connections from each source IP
+func (l *Listener) SetMaxPerIP(max int) {
+	l.adm.mu.Lock()
+	l.adm.maxPerIP = max
+	l.adm.mu.Unlock()
+}
+
+// ------------------------------------------------------------------------- //
+
+// Check a new connection, returns the reason it is rejected or nil
+//	and if admitted, the func to call when the conn is closed
+func (a *admission) admit(conn net.Conn) (release func(), reason error) {
+	ta, ok := conn.RemoteAddr().(*net.TCPAddr)
+	if !ok {
+		return nil, nil // no IP to check
+	}
+	ip := ta.IP.String()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if contains(a.deny, ta.IP) || (0 != len(a.allow) && !contains(a.allow, ta.IP)) {
+		return nil, nwk.Err_Denied
+	}
+	if 0 != a.maxPerIP && a.perIP[ip] >= a.maxPerIP {
+		return nil, nwk.Err_TooManyConns
+	}
+	if 0 != a.rate && !a.take(ip) {
+		return nil, nwk.Err_RateLimited
+	}
+	if 0 == a.maxPerIP {
+		return nil, nil
+	}
+	a.perIP[ip]++
+	return func() {
+		a.mu.Lock()
+		if a.perIP[ip]--; 0 >= a.perIP[ip] {
+			delete(a.perIP, ip)
+		}
+		a.mu.Unlock()
+	}, nil
+}
+
+// Take a token from the IPs bucket, must hold the lock
+func (a *admission) take(ip string) bool {
+	now := time.Now()
+	b, ok := a.buckets[ip]
+	if !ok {
+		if len(a.buckets) >= maxBuckets {
+			a.prune(now)
+		}
+		b = &bucket{tokens: a.burst, last: now}
+		a.buckets[ip] = b
+	}
+	b.tokens += now.Sub(b.last).Seconds() * a.rate
+	if b.tokens > a.burst {
+		b.tokens = a.burst
+	}
+	b.last = now
+	if 1 > b.tokens {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Remove any buckets that would be full by now, if none are the least
+//	recently used is removed so a flood from many IPs can't grow the map
+func (a *admission) prune(now time.Time) {
+	for ip, b := range a.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*a.rate >= a.burst {
+			delete(a.buckets, ip)
+		}
+	}
+	if len(a.buckets) < maxBuckets {
+		return
+	}
+	oldest, at := "", now
+	for ip, b := range a.buckets {
+		if !b.last.After(at) {
+			oldest, at = ip, b.last
+		}
+	}
+	delete(a.buckets, oldest)
+}
+
+func contains(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	nets := []*net.IPNet{}
+	for _, c := range cidrs {
+		if !strings.Contains(c, "/") {
+			ip := net.ParseIP(c)
+			if nil == ip {
+				return nil, nwk.Err_IllegalParam
+			}
+			bits := 8 * net.IPv6len
+			if nil != ip.To4() {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(c)
+		if nil != err {
+			return nil, nwk.Err_IllegalParam
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
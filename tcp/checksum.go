@@ -0,0 +1,200 @@
+package tcp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/adler32"
+	"hash/crc32"
+	"io"
+
+	"github.com/jayacarlson/nwk"
+)
+
+/*
+	Optional checksum trailer on records, added by WriteRecord and
+		WriteSizedRecord and checked by ReadRecord and ReadSizedRecord
+		-- the checksum is of the record data only, and follows the
+		end marker (ReadRecord) or the data (ReadSizedRecord)
+
+		Checksummer.SetChecksum( Checksum, binary.ByteOrder ):
+			Set the checksum used, with the ByteOrder of the trailer
+			(nil is BigEndian):
+				SumNone:	no trailer (default)
+				SumCRC16:	CRC-16/CCITT-FALSE (poly 0x1021, init 0xFFFF), 2 bytes
+				SumCRC32:	CRC-32 (IEEE, as zip / ethernet), 4 bytes
+				SumAdler32:	Adler-32 (as zlib), 4 bytes
+				SumXOR:		XOR of all the bytes, 1 byte
+				SumAdd:		sum of all the bytes modulo 256, 1 byte
+
+		Checksummer.WriteRecord( stRec, enRec, data []byte ) error:
+			Writes stRec, the data, enRec and any checksum, to be read
+			with ReadRecord -- the data must not hold enRec (see
+			stuffing.go for records that can hold any data)
+
+		Checksummer.WriteSizedRecord( stRec, data []byte ) error:
+			Writes stRec, the data and any checksum, to be read with
+			ReadSizedRecord
+
+		On a mismatch the reads return the record data with a
+		*ChecksumError, errors.Is(err, nwk.Err_BadData)
+
+		ChecksumOf( Checksum, []byte ) uint32:
+			Returns the checksum of the data
+*/
+
+type (
+	Checksum int
+
+	// Checksum mismatch, errors.Is(err, nwk.Err_BadData)
+	ChecksumError struct {
+		Sum  Checksum
+		Got  uint32 // checksum received
+		Want uint32 // checksum of the data received
+	}
+)
+
+const (
+	SumNone Checksum = iota
+	SumCRC16
+	SumCRC32
+	SumAdler32
+	SumXOR
+	SumAdd
+)
+
+var sumNames = [...]string{"None", "CRC-16", "CRC-32", "Adler-32", "XOR", "Sum"}
+
+func (c Checksum) String() string {
+	if c < 0 || int(c) >= len(sumNames) {
+		return fmt.Sprintf("Checksum(%d)", int(c))
+	}
+	return sumNames[c]
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("%v: %v mismatch, got %#x want %#x", nwk.Err_BadData, e.Sum, e.Got, e.Want)
+}
+func (e *ChecksumError) Unwrap() error { return nwk.Err_BadData }
+
+// Return the checksum of the data
+func ChecksumOf(sum Checksum, dta []byte) uint32 {
+	switch sum {
+	case SumCRC16:
+		crc := uint16(0xFFFF)
+		for _, b := range dta {
+			crc ^= uint16(b) << 8
+			for i := 0; i < 8; i++ {
+				if 0 != crc&0x8000 {
+					crc = crc<<1 ^ 0x1021
+				} else {
+					crc <<= 1
+				}
+			}
+		}
+		return uint32(crc)
+	case SumCRC32:
+		return crc32.ChecksumIEEE(dta)
+	case SumAdler32:
+		return adler32.Checksum(dta)
+	case SumXOR:
+		x := byte(0)
+		for _, b := range dta {
+			x ^= b
+		}
+		return uint32(x)
+	case SumAdd:
+		x := byte(0)
+		for _, b := range dta {
+			x += b
+		}
+		return uint32(x)
+	}
+	return 0
+}
+
+// ========================================================================= //
+
+func (x *readWriter) SetChecksum(sum Checksum, ord binary.ByteOrder) {
+	if nil == ord {
+		ord = binary.BigEndian
+	}
+	x.sum, x.sumOrd = sum, ord
+}
+
+func (x *readWriter) WriteRecord(stRec, enRec, dta []byte) error {
+	return x.Write(x.record(stRec, enRec, dta))
+}
+
+func (x *readWriter) WriteSizedRecord(stRec, dta []byte) error {
+	return x.Write(x.record(stRec, nil, dta))
+}
+
+// ========================================================================= //
+
+func (x *readBufWriter) SetChecksum(sum Checksum, ord binary.ByteOrder) {
+	x.r.SetChecksum(sum, ord)
+}
+
+func (x *readBufWriter) WriteRecord(stRec, enRec, dta []byte) error {
+	return x.Write(x.r.record(stRec, enRec, dta))
+}
+
+func (x *readBufWriter) WriteSizedRecord(stRec, dta []byte) error {
+	return x.Write(x.r.record(stRec, nil, dta))
+}
+
+// ------------------------------------------------------------------------- //
+
+func sumSize(sum Checksum) int {
+	switch sum {
+	case SumCRC16:
+		return 2
+	case SumCRC32, SumAdler32:
+		return 4
+	case SumXOR, SumAdd:
+		return 1
+	}
+	return 0
+}
+
+// Build a record with any checksum trailer
+func (x *readWriter) record(stRec, enRec, dta []byte) []byte {
+	rec := make([]byte, 0, len(stRec)+len(dta)+len(enRec)+4)
+	rec = append(rec, stRec...)
+	rec = append(rec, dta...)
+	rec = append(rec, enRec...)
+	var tr [4]byte
+	cs := ChecksumOf(x.sum, dta)
+	switch sumSize(x.sum) {
+	case 1:
+		tr[0] = byte(cs)
+	case 2:
+		x.sumOrd.PutUint16(tr[:], uint16(cs))
+	case 4:
+		x.sumOrd.PutUint32(tr[:], cs)
+	}
+	return append(rec, tr[:sumSize(x.sum)]...)
+}
+
+// Read and check any checksum trailer for the record data
+func (x *readWriter) checkSum(dta []byte) error {
+	n := sumSize(x.sum)
+	if 0 == n {
+		return nil
+	}
+	var tr [4]byte
+	if _, err := io.ReadFull(x.reader, tr[:n]); nil != err {
+		return nwk.ChkNetErr(noEOF(err))
+	}
+	got := uint32(tr[0])
+	switch n {
+	case 2:
+		got = uint32(x.sumOrd.Uint16(tr[:]))
+	case 4:
+		got = x.sumOrd.Uint32(tr[:])
+	}
+	if want := ChecksumOf(x.sum, dta); got != want {
+		return &ChecksumError{Sum: x.sum, Got: got, Want: want}
+	}
+	return nil
+}
@@ -2,13 +2,25 @@ package tcp
 
 import (
 	"net"
+	"sync"
 	"time"
 
 	"github.com/jayacarlson/dbg"
 	"github.com/jayacarlson/nwk"
 )
 
-var ClientDbg = dbg.Dbg{false, 0}
+var (
+	ClientDbg = dbg.Dbg{false, 0}
+
+	// If set, called after every NewClient connection attempt (set before use,
+	//	see AddDialObserver)
+	DialObserver func(srvrPort string, took time.Duration, err error)
+
+	clientConns = newListenStats() // counts for all client connections
+	dialMu      sync.Mutex
+	dialErrs    = map[error]int{}                                      // failed connection attempts by nwk.ErrClass
+	dialObs     []func(srvrPort string, took time.Duration, err error) // AddDialObserver funcs, copied on add
+)
 
 /*
 	Simple TCP client; connects to server and can read from / write to it,
@@ -21,23 +33,96 @@ var ClientDbg = dbg.Dbg{false, 0}
 				buffered:	return a buffered writer in the ReadWriter
 			On connection returns ReadWriter, or returns error
 	User must Close the client (ReadWriter)
+
+		AddDialObserver( func(srvrPort, took, err) ):
+			Add a func called after every NewClient connection attempt,
+			as DialObserver but can be called at any time and keeps any
+			others added (or DialObserver) -- e.g. for the metrics package
+
+		ClientCounters() ClientStats:
+			Returns a snapshot of the counts for all clients:
+				Dials:		number of connection attempts
+				DialErrors:	failed attempts, keyed by nwk.ErrClass
+				Open:		number of clients currently open
+				BytesIn, BytesOut:	total bytes read / written by clients
+				ConnTime:	total time of all closed clients
 */
 
+type (
+	ClientStats struct {
+		Dials      int
+		DialErrors map[error]int
+		Open       int
+		BytesIn    uint64
+		BytesOut   uint64
+		ConnTime   time.Duration
+	}
+)
+
 func NewClient(srvrPort string, timeout time.Duration, buf bool) (ReadWriter, error) {
+	return newClient("tcp", srvrPort, timeout, buf)
+}
+
+// Add a func called after every connection attempt, keeping any others
+func AddDialObserver(fn func(srvrPort string, took time.Duration, err error)) {
+	dialMu.Lock()
+	dialObs = append(dialObs[:len(dialObs):len(dialObs)], fn)
+	dialMu.Unlock()
+}
+
+// Return a snapshot of the counts for all clients
+func ClientCounters() ClientStats {
+	ls := clientConns.snapshot()
+	cs := ClientStats{
+		Dials:      ls.Connections,
+		DialErrors: map[error]int{},
+		Open:       ls.Servicing,
+		BytesIn:    ls.BytesIn,
+		BytesOut:   ls.BytesOut,
+		ConnTime:   ls.ConnTime,
+	}
+	dialMu.Lock()
+	for e, n := range dialErrs {
+		cs.DialErrors[e] = n
+		cs.Dials += n
+	}
+	dialMu.Unlock()
+	return cs
+}
+
+// ------------------------------------------------------------------------- //
+
+func newClient(network, srvrPort string, timeout time.Duration, buf bool) (ReadWriter, error) {
 	var x ReadWriter
 	var conn net.Conn
 	var err error
 
+	start := time.Now()
 	if 0 == timeout {
-		conn, err = net.Dial("tcp", srvrPort)
+		conn, err = net.Dial(network, srvrPort)
 	} else {
-		conn, err = net.DialTimeout("tcp", srvrPort, timeout)
+		conn, err = net.DialTimeout(network, srvrPort, timeout)
+	}
+	err = nwk.ChkNetErr(err)
+	took := time.Since(start)
+	if nil != DialObserver {
+		DialObserver(srvrPort, took, err)
+	}
+	dialMu.Lock()
+	observers := dialObs
+	if nil != err {
+		dialErrs[nwk.ErrClass(err)]++
+	}
+	dialMu.Unlock()
+	for _, fn := range observers {
+		fn(srvrPort, took, err)
 	}
-	if err = nwk.ChkNetErr(err); nil != err {
+	if nil != err {
 		ClientDbg.Error("netDial failed: %v", err)
 		return nil, err
 	}
 	ClientDbg.Info("Connection made to: %s", srvrPort)
+	conn = newStatConn(conn, clientConns)
 
 	if buf {
 		// return buffered reads & writes
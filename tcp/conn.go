@@ -0,0 +1,196 @@
+package tcp
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jayacarlson/nwk"
+)
+
+/*
+	Internal net.Conn wrapper handed out by the Listener, keeps track
+		of when the connection was made and the bytes passing through it
+		and updates the listener stats when closed
+
+		Can also enforce an idle timeout and a maximum lifetime, closing
+		the conn when either expires and remembering the reason why
+
+		UnwrapConn( net.Conn ) net.Conn:
+			Returns the conn from Accept / Dial for a conn given out by
+			a Listener (WaitOnConnection) or client, e.g. to get the
+			*net.TCPConn for SetKeepAlive or SetNoDelay -- other conns
+			are returned as is
+			Reads and writes should still go through the given conn,
+			or they are missing from the stats
+*/
+
+type (
+	statConn struct {
+		bytesIn  uint64 // bytes read from the remote (64bit atomics first for alignment)
+		bytesOut uint64 // bytes written to the remote
+		dur      int64  // connection duration, set when closed
+		last     int64  // time of the last read / write, for the idle timeout
+		net.Conn
+		connNum int          // connection number given by the listener
+		remote  string       // client ip:port
+		start   time.Time    // when the connection was accepted
+		ls      *listenStats // stats to update on close
+		release func()       // if set, called on close
+		once    sync.Once
+
+		mu     sync.Mutex
+		idle   time.Duration // idle timeout, 0 if none
+		idleT  *time.Timer
+		lifeT  *time.Timer
+		reason error // why the conn was closed by a timer
+		closed bool
+	}
+)
+
+func newStatConn(conn net.Conn, ls *listenStats) *statConn {
+	c := statConn{Conn: conn, remote: addrString(conn.RemoteAddr()), start: time.Now(), ls: ls}
+	c.last = c.start.UnixNano()
+	ls.opened(&c)
+	return &c
+}
+
+// ========================================================================= //
+
+func (c *statConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if 0 < n {
+		atomic.AddUint64(&c.bytesIn, uint64(n))
+		atomic.StoreInt64(&c.last, time.Now().UnixNano())
+	}
+	return n, err
+}
+
+func (c *statConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	if 0 < n {
+		atomic.AddUint64(&c.bytesOut, uint64(n))
+		atomic.StoreInt64(&c.last, time.Now().UnixNano())
+	}
+	return n, err
+}
+
+func (c *statConn) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	if nil != c.idleT {
+		c.idleT.Stop()
+	}
+	if nil != c.lifeT {
+		c.lifeT.Stop()
+	}
+	c.mu.Unlock()
+	err := c.Conn.Close()
+	c.once.Do(func() {
+		atomic.StoreInt64(&c.dur, int64(time.Since(c.start)))
+		c.ls.closed(c)
+		if nil != c.release {
+			c.release()
+		}
+	})
+	return err
+}
+
+func (c *statConn) CloseRead() error {
+	if hc, ok := c.Conn.(HalfCloser); ok {
+		return hc.CloseRead()
+	}
+	return nwk.Err_NotSupported
+}
+
+func (c *statConn) CloseWrite() error {
+	if hc, ok := c.Conn.(HalfCloser); ok {
+		return hc.CloseWrite()
+	}
+	return nwk.Err_NotSupported
+}
+
+// Return the conn from Accept / Dial
+func (c *statConn) Unwrap() net.Conn {
+	return c.Conn
+}
+
+// Return the conn a Listener or client conn wraps, or conn if not wrapped
+func UnwrapConn(conn net.Conn) net.Conn {
+	if uc, ok := conn.(interface{ Unwrap() net.Conn }); ok {
+		return uc.Unwrap()
+	}
+	return conn
+}
+
+// ------------------------------------------------------------------------- //
+
+// Return the current stats for the conn
+func (c *statConn) stats() ConnStats {
+	dur := time.Duration(atomic.LoadInt64(&c.dur))
+	if 0 == dur {
+		dur = time.Since(c.start)
+	}
+	return ConnStats{
+		ConnNum:  c.connNum,
+		Remote:   c.remote,
+		Start:    c.start,
+		Duration: dur,
+		BytesIn:  atomic.LoadUint64(&c.bytesIn),
+		BytesOut: atomic.LoadUint64(&c.bytesOut),
+	}
+}
+
+// Start the idle and lifetime timers, 0 for none
+func (c *statConn) limit(idle, life time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if 0 != idle {
+		c.idle = idle
+		c.idleT = time.AfterFunc(idle, c.idleCheck)
+	}
+	if 0 != life {
+		c.lifeT = time.AfterFunc(life-time.Since(c.start), func() { c.expire(nwk.Err_MaxLifetime) })
+	}
+}
+
+// Idle timer expired, close the conn unless there was activity since
+func (c *statConn) idleCheck() {
+	left := c.idle - time.Since(time.Unix(0, atomic.LoadInt64(&c.last)))
+	if 0 < left {
+		c.mu.Lock()
+		if !c.closed {
+			c.idleT.Reset(left)
+		}
+		c.mu.Unlock()
+		return
+	}
+	c.expire(nwk.Err_IdleTimeout)
+}
+
+func (c *statConn) expire(reason error) {
+	c.mu.Lock()
+	if c.closed || nil != c.reason {
+		c.mu.Unlock()
+		return
+	}
+	c.reason = reason
+	c.mu.Unlock()
+	c.Conn.Close() // unblocks any read / write, the user still calls Close
+}
+
+// Return why a timer closed the conn, nil if it wasn't
+func (c *statConn) closeReason() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.reason
+}
+
+// Return the address as a string, unix peers are often unnamed
+func addrString(a net.Addr) string {
+	if nil == a || "" == a.String() {
+		return "@"
+	}
+	return a.String()
+}
@@ -0,0 +1,12 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd
+
+package tcp
+
+import (
+	"github.com/jayacarlson/nwk"
+)
+
+func dupFD(fd uintptr) (uintptr, error) {
+	return 0, nwk.Err_NotSupported
+}
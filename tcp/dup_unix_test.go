@@ -0,0 +1,14 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+// +build linux darwin dragonfly freebsd netbsd openbsd
+
+package tcp
+
+import (
+	"syscall"
+)
+
+// Duplicate an fd, so a copy can be handed off and the original closed
+func dupFD(fd uintptr) (uintptr, error) {
+	n, err := syscall.Dup(int(fd))
+	return uintptr(n), err
+}
@@ -0,0 +1,82 @@
+package tcp
+
+import (
+	"fmt"
+	"time"
+)
+
+/*
+	Typed events sent by a Listener, an alternative to parsing the
+		formatted status strings
+
+		NewEventListener( ListenIP, Events chan ) ( *Listener, error ):
+			Same as NewListener, but sends ListenerEvents through
+			the Events channel instead of status strings
+
+		ListenerEvent.String() string:
+			Returns the event in the same format as the status
+			strings sent by NewListener:
+				"Listener Created"
+				"Listener Waiting"
+				"Listener Closed"
+				Con<connection#>@<clientIP>
+				Dis<connection#>@<clientIP>(<resultErr>)
+				Rej@<clientIP>(<reason>)
+
+		StatusAdapter( Events chan, Status chan ):
+			Converts ListenerEvents to status strings for any
+			existing status consumers, exits when Events is closed
+			Can be used as a GO ROUTINE
+*/
+
+type (
+	EventKind int
+
+	ListenerEvent struct {
+		Kind     EventKind
+		ConnNum  int           // connection number (for ref only), 0 on Rejected
+		Remote   string        // client ip:port
+		Duration time.Duration // time connected, set on Disconnected
+		BytesIn  uint64        // bytes read from the client, set on Disconnected
+		BytesOut uint64        // bytes written to the client, set on Disconnected
+		Err      error         // handler result on Disconnected, reason on Rejected
+	}
+)
+
+const (
+	EvCreated EventKind = iota
+	EvWaiting
+	EvConnected
+	EvDisconnected
+	EvClosed
+	EvRejected
+)
+
+var eventNames = [...]string{"Created", "Waiting", "Connected", "Disconnected", "Closed", "Rejected"}
+
+func (k EventKind) String() string {
+	if k < 0 || int(k) >= len(eventNames) {
+		return fmt.Sprintf("EventKind(%d)", int(k))
+	}
+	return eventNames[k]
+}
+
+// Return the event in the legacy status string format
+func (e ListenerEvent) String() string {
+	switch e.Kind {
+	case EvConnected:
+		return fmt.Sprintf("Con%d@%s", e.ConnNum, e.Remote)
+	case EvDisconnected:
+		return fmt.Sprintf("Dis%d@%s(%v)", e.ConnNum, e.Remote, e.Err)
+	case EvRejected:
+		return fmt.Sprintf("Rej@%s(%v)", e.Remote, e.Err)
+	}
+	return "Listener " + e.Kind.String()
+}
+
+// Convert events to status strings until the events chan is closed
+func StatusAdapter(events <-chan ListenerEvent, status chan<- string) {
+	for e := range events {
+		status <- e.String()
+	}
+}
@@ -0,0 +1,228 @@
+package tcp
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+
+	"github.com/jayacarlson/nwk"
+)
+
+/*
+	Length-prefixed frames, for binary data that may hold any byte value
+		-- each frame is the length of the data followed by the data
+
+		Framer.SetFraming( FramePrefix, binary.ByteOrder, maxSize int ):
+			Set the length prefix used by the frame funcs:
+				FrameU8, FrameU16, FrameU32:	fixed size unsigned length
+					in the given ByteOrder (nil is BigEndian)
+				FrameVarint:	unsigned varint (as encoding/binary,
+					the ByteOrder is not used)
+			and the largest frame allowed, 0 for no limit other than
+			the prefix size
+			Defaults to FrameU32, BigEndian and DefaultMaxFrame
+
+		Framer.ReadFrame() ( []byte, error ):
+			Reads a frame and returns its data
+			nwk.Err_TooLarge if the length is over the maxSize, the
+			data is left unread (the conn should then be closed)
+			io.ErrUnexpectedEOF if the conn closes within a frame
+
+		Framer.ReadFrameInto( []byte ) ( int, error ):
+			Reads a frame into the buffer, returns the data length
+			A frame that doesn't fit the buffer is read and discarded,
+			returning io.ErrShortBuffer
+			Otherwise the same as ReadFrame
+
+		Framer.WriteFrame( []byte ) error:
+			Writes the length prefix and data, nwk.Err_TooLarge if the
+			data is over the maxSize or won't fit the prefix
+*/
+
+type (
+	FramePrefix int
+
+	framing struct {
+		prefix FramePrefix
+		ord    binary.ByteOrder
+		max    int
+	}
+)
+
+const (
+	FrameU32 FramePrefix = iota
+	FrameU16
+	FrameU8
+	FrameVarint
+)
+
+const DefaultMaxFrame = 1 << 20 // 1MB
+
+// ========================================================================= //
+
+func (x *readWriter) SetFraming(prefix FramePrefix, ord binary.ByteOrder, maxSize int) {
+	if nil == ord {
+		ord = binary.BigEndian
+	}
+	x.frame = framing{prefix: prefix, ord: ord, max: maxSize}
+}
+
+func (x *readWriter) ReadFrame() ([]byte, error) {
+	n, err := x.readFrameLen()
+	if nil != err {
+		return nil, err
+	}
+	data := make([]byte, n)
+	_, err = io.ReadFull(x.reader, data)
+	return data, nwk.ChkNetErr(noEOF(err))
+}
+
+func (x *readWriter) ReadFrameInto(buf []byte) (int, error) {
+	n, err := x.readFrameLen()
+	if nil != err {
+		return 0, err
+	}
+	if n > len(buf) {
+		_, err = x.reader.Discard(n)
+		if nil != err {
+			return 0, nwk.ChkNetErr(noEOF(err))
+		}
+		return 0, io.ErrShortBuffer
+	}
+	_, err = io.ReadFull(x.reader, buf[:n])
+	return n, nwk.ChkNetErr(noEOF(err))
+}
+
+func (x *readWriter) WriteFrame(dta []byte) error {
+	hdr, err := x.frame.header(len(dta))
+	if nil != err {
+		return err
+	}
+	return x.Write(append(hdr, dta...))
+}
+
+// ========================================================================= //
+
+func (x *readBufWriter) SetFraming(prefix FramePrefix, ord binary.ByteOrder, maxSize int) {
+	x.r.SetFraming(prefix, ord, maxSize)
+}
+
+func (x *readBufWriter) ReadFrame() ([]byte, error) {
+	if err := x.flushRead(); nil != err {
+		return nil, err
+	}
+	return x.r.ReadFrame()
+}
+
+func (x *readBufWriter) ReadFrameInto(buf []byte) (int, error) {
+	if err := x.flushRead(); nil != err {
+		return 0, err
+	}
+	return x.r.ReadFrameInto(buf)
+}
+
+func (x *readBufWriter) WriteFrame(dta []byte) error {
+	hdr, err := x.r.frame.header(len(dta))
+	if nil != err {
+		return err
+	}
+	if err = x.Write(hdr); nil != err {
+		return err
+	}
+	return x.Write(dta)
+}
+
+// ------------------------------------------------------------------------- //
+
+// Read the length prefix of the next frame
+func (x *readWriter) readFrameLen() (int, error) {
+	x.setRExpiry()
+	var n uint64
+	var hdr [4]byte
+	var err error
+	switch x.frame.prefix {
+	case FrameU8:
+		_, err = io.ReadFull(x.reader, hdr[:1])
+		n = uint64(hdr[0])
+	case FrameU16:
+		_, err = io.ReadFull(x.reader, hdr[:2])
+		n = uint64(x.frame.ord.Uint16(hdr[:]))
+	case FrameU32:
+		_, err = io.ReadFull(x.reader, hdr[:4])
+		n = uint64(x.frame.ord.Uint32(hdr[:]))
+	case FrameVarint:
+		n, err = readUvarint(x.reader)
+	default:
+		return 0, nwk.Err_IllegalParam
+	}
+	if nil != err {
+		return 0, nwk.ChkNetErr(err)
+	}
+	if (0 != x.frame.max && n > uint64(x.frame.max)) || n > math.MaxInt32 {
+		return 0, nwk.Err_TooLarge
+	}
+	return int(n), nil
+}
+
+// Return the length prefix for a frame of n bytes
+func (f framing) header(n int) ([]byte, error) {
+	if 0 != f.max && n > f.max {
+		return nil, nwk.Err_TooLarge
+	}
+	switch f.prefix {
+	case FrameU8:
+		if math.MaxUint8 < n {
+			return nil, nwk.Err_TooLarge
+		}
+		return []byte{byte(n)}, nil
+	case FrameU16:
+		if math.MaxUint16 < n {
+			return nil, nwk.Err_TooLarge
+		}
+		hdr := make([]byte, 2)
+		f.ord.PutUint16(hdr, uint16(n))
+		return hdr, nil
+	case FrameU32:
+		if math.MaxUint32 < uint64(n) {
+			return nil, nwk.Err_TooLarge
+		}
+		hdr := make([]byte, 4)
+		f.ord.PutUint32(hdr, uint32(n))
+		return hdr, nil
+	case FrameVarint:
+		hdr := make([]byte, binary.MaxVarintLen64)
+		return hdr[:binary.PutUvarint(hdr, uint64(n))], nil
+	}
+	return nil, nwk.Err_IllegalParam
+}
+
+// binary.ReadUvarint, with only an overflow as nwk.Err_BadData, read
+//	errors (timeouts...) are returned as is
+func readUvarint(r io.ByteReader) (uint64, error) {
+	var n uint64
+	for i := 0; i < binary.MaxVarintLen64; i++ {
+		b, err := r.ReadByte()
+		if nil != err {
+			if 0 < i {
+				err = noEOF(err)
+			}
+			return 0, err
+		}
+		if 0x80 > b {
+			if binary.MaxVarintLen64-1 == i && 1 < b {
+				return 0, nwk.Err_BadData // overflows a uint64
+			}
+			return n | uint64(b)<<(7*i), nil
+		}
+		n |= uint64(b&0x7F) << (7 * i)
+	}
+	return 0, nwk.Err_BadData
+}
+
+// EOF part way through data is an unexpected EOF
+func noEOF(err error) error {
+	if io.EOF == err {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,136 @@
+package tcp
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/jayacarlson/nwk"
+)
+
+/*
+	Passing a listening socket to another process, e.g. to restart a
+		server with a new version without refusing any connections --
+		the old process hands the socket to the new one, then stops
+		accepting and finishes the connections it has
+
+		Listener.File() ( *os.File, error ):
+			Returns a copy of the listening socket, closing the Listener
+			doesn't close the File (and vice versa)
+
+		Listener.PassTo( *exec.Cmd, EnvVar ) error:
+			Adds the listening socket to the ExtraFiles of the command
+			and sets EnvVar in its environment to the fd number, for
+			NewListenerFromEnv in the new process -- call before Start
+			If cmd.Env is nil it is set to the current environment
+			The socket added is a copy (see File), the caller must
+			close its ExtraFiles entry after cmd.Start
+
+		NewListenerFromFD( fd uintptr, Status chan ) ( *Listener, error ):
+			Creates a Listener from an inherited listening socket, TCP
+			or unix stream, the fd is closed (the Listener holds its
+			own copy)
+
+		NewListenerFromEnv( EnvVar, Status chan ) ( *Listener, error ):
+			Same as NewListenerFromFD, with the fd number read from
+			the environment variable -- nwk.Err_AddrNotFound if unset
+
+		SystemdListeners( Status chan ) ( []*Listener, error ):
+			Creates Listeners from the sockets passed by systemd socket
+			activation (LISTEN_PID, LISTEN_FDS, fds starting at 3), the
+			environment variables are removed so child processes don't
+			use them -- nwk.Err_AddrNotFound if not socket activated
+
+		The status chan gets the same messages as for NewListener
+*/
+
+const listenFDsStart = 3 // first fd passed by systemd / ExtraFiles
+
+// ========================================================================= //
+
+// Return a copy of the listening socket
+func (l *Listener) File() (*os.File, error) {
+	f, err := l.listener.File()
+	return f, nwk.ChkNetErr(err)
+}
+
+// Pass the listening socket to a command, its fd number is set in envVar
+func (l *Listener) PassTo(cmd *exec.Cmd, envVar string) error {
+	f, err := l.File()
+	if nil != err {
+		return err
+	}
+	if nil == cmd.Env {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%d", envVar, listenFDsStart+len(cmd.ExtraFiles)))
+	cmd.ExtraFiles = append(cmd.ExtraFiles, f)
+	return nil
+}
+
+// create a listener from an inherited listening socket
+func NewListenerFromFD(fd uintptr, status chan<- string) (*Listener, error) {
+	return newListenerFromFile(os.NewFile(fd, "fd"+strconv.Itoa(int(fd))), status)
+}
+
+// create a listener from a listening socket with the fd in envVar
+func NewListenerFromEnv(envVar string, status chan<- string) (*Listener, error) {
+	v, ok := os.LookupEnv(envVar)
+	if !ok {
+		return nil, nwk.Err_AddrNotFound
+	}
+	fd, err := strconv.Atoi(strings.TrimSpace(v))
+	if nil != err || 0 > fd {
+		return nil, nwk.Err_IllegalParam
+	}
+	return NewListenerFromFD(uintptr(fd), status)
+}
+
+// create listeners from the sockets passed by systemd socket activation
+func SystemdListeners(status chan<- string) ([]*Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if nil != err || pid != os.Getpid() {
+		return nil, nwk.Err_AddrNotFound
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if nil != err || 1 > n {
+		return nil, nwk.Err_AddrNotFound
+	}
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	ls := []*Listener{}
+	for fd := listenFDsStart; fd < listenFDsStart+n; fd++ {
+		l, err := NewListenerFromFD(uintptr(fd), status)
+		if nil != err {
+			for _, l := range ls {
+				l.Close()
+			}
+			return nil, err
+		}
+		ls = append(ls, l)
+	}
+	return ls, nil
+}
+
+// ------------------------------------------------------------------------- //
+
+func newListenerFromFile(f *os.File, status chan<- string) (*Listener, error) {
+	defer f.Close() // FileListener holds a dup of the fd
+	fl, err := net.FileListener(f)
+	if ListenDbg.ChkErr(err) {
+		return nil, nwk.ChkNetErr(err)
+	}
+	nl, ok := fl.(netListener)
+	if !ok {
+		fl.Close()
+		return nil, nwk.Err_IllegalParam
+	}
+	l := Listener{stats: newListenStats(), adm: newAdmission(), hostIP: nl.Addr().String(), statPipe: status, listener: nl}
+	l.event(ListenerEvent{Kind: EvCreated})
+	return &l, nil
+}
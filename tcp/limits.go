@@ -0,0 +1,143 @@
+package tcp
+
+import (
+	"bytes"
+	"net"
+
+	"github.com/jayacarlson/nwk"
+)
+
+/*
+	Limits on how much a ReadWriter reads, so a peer that never sends
+		the EOL or end marker (or just keeps sending) can't use up all
+		the memory -- all return nwk.Err_TooLarge, with whatever was
+		read up to the limit (the rest is left unread, so the conn
+		should normally be closed)
+
+		Limiter.SetMaxLine( int ):
+			Set the longest line (including the EOL) ReadBytes and
+			ReadString will read, 0 is no limit
+
+		Limiter.SetMaxRecord( int ):
+			Set the largest record ReadRecord, ReadSizedRecord and
+			ReadStuffed (the encoded size) will read, 0 is no limit
+
+		Limiter.SetReadBudget( int64 ):
+			Set the total number of bytes that can be read from the
+			conn, counted from when the ReadWriter was created, 0 is
+			no limit -- once used up every read returns the error
+			(anything already buffered can still be read)
+
+		Limiter.BytesRead() int64:
+			Returns the number of bytes read from the conn so far,
+			including any buffered but not yet returned
+*/
+
+type (
+	// Counts the bytes read from the conn, stopping at the budget
+	budgetReader struct {
+		conn   net.Conn
+		used   int64
+		budget int64 // 0 is no limit
+	}
+)
+
+func (b *budgetReader) Read(p []byte) (int, error) {
+	if 0 != b.budget {
+		left := b.budget - b.used
+		if 0 >= left {
+			return 0, nwk.Err_TooLarge
+		}
+		if int64(len(p)) > left {
+			p = p[:left]
+		}
+	}
+	n, err := b.conn.Read(p)
+	b.used += int64(n)
+	return n, err
+}
+
+// ========================================================================= //
+
+func (x *readWriter) SetMaxLine(max int) {
+	x.maxLine = max
+}
+
+func (x *readWriter) SetMaxRecord(max int) {
+	x.maxRecord = max
+}
+
+func (x *readWriter) SetReadBudget(budget int64) {
+	x.src.budget = budget
+}
+
+func (x *readWriter) BytesRead() int64 {
+	return x.src.used
+}
+
+// ========================================================================= //
+
+func (x *readBufWriter) SetMaxLine(max int) {
+	x.r.SetMaxLine(max)
+}
+
+func (x *readBufWriter) SetMaxRecord(max int) {
+	x.r.SetMaxRecord(max)
+}
+
+func (x *readBufWriter) SetReadBudget(budget int64) {
+	x.r.SetReadBudget(budget)
+}
+
+func (x *readBufWriter) BytesRead() int64 {
+	return x.r.BytesRead()
+}
+
+// ------------------------------------------------------------------------- //
+
+// Read up to and including delim, nwk.Err_TooLarge past max bytes (0 for no limit)
+//	-- nothing past the max is consumed
+func (x *readWriter) readDelim(delim byte, max int) ([]byte, error) {
+	if 0 == max {
+		return x.reader.ReadBytes(delim)
+	}
+	return x.readUntil(max, func(buf []byte) int {
+		return bytes.IndexByte(buf, delim)
+	})
+}
+
+// Read up to and including the byte at the index find returns (-1 if not
+//	in buf), nwk.Err_TooLarge past max bytes (0 for no limit)
+func (x *readWriter) readUntil(max int, find func([]byte) int) ([]byte, error) {
+	var line []byte
+	for {
+		if _, err := x.reader.Peek(1); nil != err {
+			return line, err
+		}
+		buf, _ := x.reader.Peek(x.reader.Buffered())
+		n, found := len(buf), false
+		if i := find(buf); 0 <= i {
+			n, found = i+1, true
+		}
+		if 0 != max && len(line)+n > max {
+			n = max - len(line)
+			line = append(line, buf[:n]...)
+			x.reader.Discard(n)
+			return line, nwk.Err_TooLarge
+		}
+		line = append(line, buf[:n]...)
+		x.reader.Discard(n)
+		if found {
+			return line, nil
+		}
+	}
+}
+
+// Return the scan limit for the end marker of a record, and the error
+//	when reached
+func (x *readWriter) recordLimit(enRec []byte) (int, error) {
+	if 0 != x.maxRecord && (0 == x.scanLimit || x.maxRecord+len(enRec) < x.scanLimit) {
+		return x.maxRecord + len(enRec), nwk.Err_TooLarge
+	}
+	return x.scanLimit, nwk.Err_ScanLimit
+}
@@ -0,0 +1,163 @@
+package tcp
+
+import (
+	"bytes"
+
+	"github.com/jayacarlson/nwk"
+)
+
+/*
+	Line handling for ReadBytes, ReadString and WriteLine -- both read
+		methods use the same line mode, lines are still capped by
+		SetMaxLine (the cap includes the terminator)
+
+		Line modes:
+			LineEOL:	lines end with the EOL byte, '\n' unless changed
+						with SetEOL (the default)
+			LineLF:		lines end with '\n' (sets the EOL byte)
+			LineCR:		lines end with '\r' (sets the EOL byte)
+			LineCRLF:	lines end with "\r\n", a lone '\n' or '\r' is
+						part of the line
+			LineAny:	lines end with "\r\n", '\n' or '\r' -- an '\n'
+						directly after a line ending in '\r' is dropped,
+						even if it arrives later
+
+		Liner.SetLineMode( LineMode, bool ):
+			Set the line mode, and whether ReadBytes and ReadString strip
+			the terminator from the lines they return
+			SetEOL switches back to LineEOL with the given byte
+
+		Liner.WriteLine( string ) error:
+			Writes the string followed by the terminator for the line
+			mode, "\n" for LineAny
+*/
+
+type (
+	LineMode int
+)
+
+const (
+	LineEOL LineMode = iota
+	LineLF
+	LineCR
+	LineCRLF
+	LineAny
+)
+
+var crlf = []byte("\r\n")
+
+// ========================================================================= //
+
+func (x *readWriter) SetLineMode(mode LineMode, strip bool) {
+	switch mode {
+	case LineLF:
+		x.eol, mode = '\n', LineEOL
+	case LineCR:
+		x.eol, mode = '\r', LineEOL
+	}
+	x.lineMode, x.stripEOL = mode, strip
+}
+
+func (x *readWriter) WriteLine(s string) error {
+	return x.WriteString(s + x.lineEnd())
+}
+
+// ========================================================================= //
+
+func (x *readBufWriter) SetLineMode(mode LineMode, strip bool) {
+	x.r.SetLineMode(mode, strip)
+}
+
+func (x *readBufWriter) WriteLine(s string) error {
+	return x.WriteString(s + x.r.lineEnd())
+}
+
+// ------------------------------------------------------------------------- //
+
+// Terminator written by WriteLine
+func (x *readWriter) lineEnd() string {
+	switch x.lineMode {
+	case LineCRLF:
+		return "\r\n"
+	case LineAny:
+		return "\n"
+	}
+	return string(x.eol)
+}
+
+// Read a line for the line mode, stripping the terminator if set
+func (x *readWriter) readLine() ([]byte, error) {
+	var line []byte
+	var err error
+	switch x.lineMode {
+	case LineCRLF:
+		line, err = x.readCRLF()
+	case LineAny:
+		line, err = x.readAny()
+	default:
+		line, err = x.readDelim(x.eol, x.maxLine)
+	}
+	if nil == err && x.stripEOL {
+		line = x.strip(line)
+	}
+	return line, err
+}
+
+// Read until "\r\n", a lone '\n' is kept in the line
+func (x *readWriter) readCRLF() ([]byte, error) {
+	var line []byte
+	for {
+		max := 0
+		if 0 != x.maxLine {
+			if max = x.maxLine - len(line); 0 >= max {
+				return line, nwk.Err_TooLarge
+			}
+		}
+		frag, err := x.readDelim('\n', max)
+		line = append(line, frag...)
+		if nil != err || bytes.HasSuffix(line, crlf) {
+			return line, err
+		}
+	}
+}
+
+// Read until "\r\n", '\n' or '\r'
+func (x *readWriter) readAny() ([]byte, error) {
+	if x.crPend { // last line ended in '\r', drop a following '\n'
+		b, err := x.reader.Peek(1)
+		if nil != err {
+			return nil, err
+		}
+		x.crPend = false
+		if '\n' == b[0] {
+			x.reader.Discard(1)
+		}
+	}
+	line, err := x.readUntil(x.maxLine, func(buf []byte) int {
+		return bytes.IndexAny(buf, "\r\n")
+	})
+	if nil != err || '\r' != line[len(line)-1] {
+		return line, err
+	}
+	// only look at what has arrived, so a peer sending just '\r' isn't kept waiting
+	if 0 == x.reader.Buffered() || (0 != x.maxLine && len(line) == x.maxLine) {
+		x.crPend = true
+	} else if b, _ := x.reader.Peek(1); '\n' == b[0] {
+		x.reader.Discard(1)
+		line = append(line, '\n')
+	}
+	return line, nil
+}
+
+func (x *readWriter) strip(line []byte) []byte {
+	switch x.lineMode {
+	case LineCRLF:
+		return bytes.TrimSuffix(line, crlf)
+	case LineAny:
+		return bytes.TrimRight(line, "\r\n")
+	}
+	if n := len(line); 0 != n && x.eol == line[n-1] {
+		return line[:n-1]
+	}
+	return line
+}
@@ -1,8 +1,11 @@
 package tcp
 
 import (
-	"fmt"
+	"context"
 	"net"
+	"os"
+	"runtime/debug"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -21,7 +24,7 @@ var ListenDbg = dbg.Dbg{false, 0}
 			TCP utility to wait on TCP connections
 				ListenIP: local interface ip:port to listen on
 				Status:   Channel to receive status messages:
-							"Listener Started" on startup
+							"Listener Created" on startup
 							"Listener Waiting" each WaitOnConnection
 							"Listener Closed" finally sent on close
 						  If using the HandleRequest(s) functions,
@@ -31,6 +34,81 @@ var ListenDbg = dbg.Dbg{false, 0}
 						    Dis<connection#>@<clientIP>(<resultErr>)
 								e.g. Dis15@127.0.0.1:47556(EOF)
 
+		NewEventListener( ListenIP, Events chan ) ( *Listener, error ):
+			Same as NewListener, but the status is sent as
+			ListenerEvents (see event.go) through Events
+
+		NewListenerConfig / NewEventListenerConfig:
+			Same as above, setting socket options such as SO_REUSEPORT
+			before binding (see sockopt.go)
+
+		NewListenerFromFD / NewListenerFromEnv / SystemdListeners:
+			Create Listeners from inherited sockets, and Listener.File /
+			PassTo to hand one to a new process (see handoff.go)
+
+		NewUnixListener / NewUnixEventListener:
+			Same as NewListener / NewEventListener for a unix stream
+			socket, see unix.go for NewUnixClient and PeerCred
+
+		Listener.SetTimeout( time.Duration ):
+			Set the timeout used by WaitOnConnection, 0 is no timeout
+
+		Listener.SetIdleHandler( IdleHandler ):
+			Set a func called by HandleRequests each time no connection
+			arrives within the timeout, HandleRequests keeps serving
+			unless the IdleHandler returns true (stop) -- without an
+			IdleHandler HandleRequests exits with the "Timedout" error
+
+		Listener.SetConnTimeouts( read, write time.Duration ):
+			Set the default ReadTimeout and WriteTimeout of the
+			ReadWriter given to each ConnHandler, 0 is no expiry
+
+		Listener.SetIdleTimeout( time.Duration ):
+			Close any connection being handled by a ConnHandler that
+			has no reads or writes for the duration, 0 is no timeout
+			The Dis message / event reports "Idle timeout"
+
+		Listener.SetMaxLifetime( time.Duration ):
+			Close any connection being handled by a ConnHandler that
+			has been open for the duration, 0 is no limit
+			The Dis message / event reports "Connection lifetime exceeded"
+
+		Listener.SetBufferedWrites( buffered, flushOnRead bool ):
+			If buffered the ConnHandler is given a ReadWriter with
+			buffered writes (see NewReadBufWriter), any writes still
+			buffered when the ConnHandler returns are flushed
+			With flushOnRead any buffered writes are flushed before
+			each read, so a request/response exchange doesn't wait
+			on data that was never sent
+
+		Listener.SetRePanic( bool ):
+			A panic in a ConnHandler is recovered, the connection closed
+			and the handler result is a *nwk.PanicError (Err_HandlerPanic)
+			holding the panic value and stack
+			With rePanic set, the panic is raised again after the
+			connection is cleaned up, e.g. for debugging -- the value
+			passed to panic is the *nwk.PanicError, so a recover higher
+			up gets the original value (Value) and the stack
+
+		Listener.Allow / Deny / SetRateLimit / SetMaxPerIP:
+			Admission control for new connections (see admission.go)
+
+		Listener.SetObserver( func(ListenerEvent) ):
+			Set a func called with every ListenerEvent, in addition to
+			any status or event chan -- e.g. for collecting metrics
+			The func is called inline and should not block
+			Replaces any func set before, nil to remove
+
+		Listener.AddObserver( func(ListenerEvent) ):
+			Add another func called with every ListenerEvent, as
+			SetObserver, without replacing the func it set or any
+			others added -- e.g. for the metrics package
+			Both can be called while the Listener is serving
+
+		Listener.Addr() string:
+			Returns the address the listener is bound to, e.g. the
+			port picked when created with port 0 ("127.0.0.1:0")
+
 		Listener.Close():
 			Close the listener
 
@@ -38,6 +116,9 @@ var ListenDbg = dbg.Dbg{false, 0}
 			Returns the current number of connections being served
 			and the total number of connections handled
 
+		Listener.Stats() ListenerStats:
+			Returns a snapshot of the connection statistics (see stats.go)
+
 		Listener.WaitOnConnection() ( net.Conn, int, error ):
 			Waits until request made on listener returns
 				net.Conn which MUST BE CLOSED BY THE USER
@@ -45,6 +126,9 @@ var ListenDbg = dbg.Dbg{false, 0}
 				any error
 			Retuns a net.Conn and not a ReadWriter in case you
 			want to do something more complex with the net.Conn.
+			The net.Conn is a wrapper keeping the conn stats, not the
+			*net.TCPConn (or *net.UnixConn) itself -- use UnwrapConn
+			to get that, e.g. UnwrapConn(conn).(*net.TCPConn)
 			For simplicity there is the ConnHandler type which
 			will handle simple read/writes by returning a
 			simple ReadWriter and will close the conn afterwords.
@@ -69,33 +153,47 @@ var ListenDbg = dbg.Dbg{false, 0}
 */
 
 type (
+	// Called when no connection arrives within the listen timeout,
+	//	return true to have HandleRequests stop serving
+	IdleHandler func(l *Listener) (stop bool)
+
+	// Accepting side of a *net.TCPListener or *net.UnixListener
+	netListener interface {
+		net.Listener
+		SetDeadline(t time.Time) error
+		File() (*os.File, error)
+	}
+
 	Listener struct {
-		connections uint32           // total number of connections made
-		servicing   uint32           // number of active connections, updated by handleConn func
-		hostIP      string           // host IP and port
-		statPipe    chan<- string    // chan for any status output
-		timeout     time.Duration    // listen timeout for WaitOnConnect
-		listener    *net.TCPListener // actual TCP listener
+		stats     *listenStats         // connection counts and stats
+		adm       *admission           // allow/deny and rate limits
+		hostIP    string               // host IP and port
+		statPipe  chan<- string        // chan for any status output
+		evtPipe   chan<- ListenerEvent // chan for any event output
+		obsMu     sync.Mutex
+		observer  func(ListenerEvent)   // func called with each event (SetObserver)
+		observers []func(ListenerEvent) // more funcs called (AddObserver), copied on add
+		timeout   time.Duration         // listen timeout for WaitOnConnect
+		idle      IdleHandler           // called by HandleRequests on listen timeout
+		rTimeout  time.Duration         // default ReadWriter read timeout
+		wTimeout  time.Duration         // default ReadWriter write timeout
+		idleTime  time.Duration         // idle timeout for handled connections
+		maxLife   time.Duration         // max lifetime of handled connections
+		bufWrite  bool                  // handlers get a buffered writer
+		rdFlush   bool                  // buffered writer flushes before reads
+		rePanic   bool                  // raise handler panics after cleanup
+		listener  netListener           // actual TCP or unix listener
 	}
 )
 
 // create a TCP listener, waiting on connections from remote (client) PCs
 func NewListener(ipPort string, status chan<- string) (*Listener, error) {
-	l := Listener{hostIP: ipPort, statPipe: status}
-
-	tcpa, err := net.ResolveTCPAddr("tcp", ipPort)
-	if ListenDbg.ChkErr(err) {
-		return nil, err
-	}
-
-	listener, err := net.ListenTCP("tcp", tcpa)
-	if ListenDbg.ChkErr(err) {
-		return nil, err
-	}
-	l.listener = listener
-	l.status("Listener Created")
+	return newListener(ipPort, status, nil)
+}
 
-	return &l, nil
+// create a TCP listener that reports its status as ListenerEvents
+func NewEventListener(ipPort string, events chan<- ListenerEvent) (*Listener, error) {
+	return newListener(ipPort, nil, events)
 }
 
 // ========================================================================= //
@@ -105,15 +203,64 @@ func (l *Listener) SetTimeout(timeout time.Duration) {
 	l.timeout = timeout
 }
 
+// Set the func called by HandleRequests when the listen timeout expires
+func (l *Listener) SetIdleHandler(fn IdleHandler) {
+	l.idle = fn
+}
+
+// Set the default read & write timeouts for handled connections
+func (l *Listener) SetConnTimeouts(read, write time.Duration) {
+	l.rTimeout, l.wTimeout = read, write
+}
+
+// Set the idle timeout for handled connections
+func (l *Listener) SetIdleTimeout(idle time.Duration) {
+	l.idleTime = idle
+}
+
+// Set the maximum lifetime of handled connections
+func (l *Listener) SetMaxLifetime(life time.Duration) {
+	l.maxLife = life
+}
+
+// Set handlers to use buffered writes, optionally flushed before each read
+func (l *Listener) SetBufferedWrites(buffered, flushOnRead bool) {
+	l.bufWrite, l.rdFlush = buffered, flushOnRead
+}
+
+// Set to raise any handler panic again after the connection is closed
+func (l *Listener) SetRePanic(rePanic bool) {
+	l.rePanic = rePanic
+}
+
+// Set the func called with every event
+func (l *Listener) SetObserver(fn func(ListenerEvent)) {
+	l.obsMu.Lock()
+	l.observer = fn
+	l.obsMu.Unlock()
+}
+
+// Add a func called with every event, keeping any others
+func (l *Listener) AddObserver(fn func(ListenerEvent)) {
+	l.obsMu.Lock()
+	l.observers = append(l.observers[:len(l.observers):len(l.observers)], fn)
+	l.obsMu.Unlock()
+}
+
+// Return the actual address the listener is bound to
+func (l *Listener) Addr() string {
+	return l.listener.Addr().String()
+}
+
 // Close the listener
 func (l *Listener) Close() {
 	l.listener.Close()
-	l.status("Listener Closed")
+	l.event(ListenerEvent{Kind: EvClosed})
 }
 
 // Return the current and total number of connections
 func (l *Listener) Counts() (servicing, totalConnections int) {
-	return int(atomic.LoadUint32(&l.servicing)), int(atomic.LoadUint32(&l.connections))
+	return int(atomic.LoadUint32(&l.stats.servicing)), int(atomic.LoadUint32(&l.stats.connections))
 }
 
 /*
@@ -129,14 +276,28 @@ func (l *Listener) WaitOnConnection() (net.Conn, int, error) {
 		expiry = time.Now().Add(l.timeout)
 	}
 	l.listener.SetDeadline(expiry)
-	l.status("Listener Waiting")
-	conn, err := l.listener.Accept()
-	err = nwk.ChkNetErr(err)
-	if ListenDbg.ChkErrI(err, []error{nwk.Err_NoConnection}) {
-		return nil, -1, err
+	for {
+		l.event(ListenerEvent{Kind: EvWaiting})
+		conn, err := l.listener.Accept()
+		err = nwk.ChkNetErr(err)
+		if ListenDbg.ChkErrI(err, []error{nwk.Err_NoConnection}) {
+			if nwk.Err_NoConnection != err && nwk.Err_Timeout != err {
+				l.stats.acceptErr()
+			}
+			return nil, -1, err
+		}
+		release, reason := l.adm.admit(conn)
+		if nil != reason {
+			remote := addrString(conn.RemoteAddr())
+			conn.Close()
+			l.stats.rejected(reason)
+			l.event(ListenerEvent{Kind: EvRejected, Remote: remote, Err: reason})
+			continue
+		}
+		sc := newStatConn(conn, l.stats)
+		sc.release = release
+		return sc, sc.connNum, nil
 	}
-	atomic.AddUint32(&l.servicing, 1)
-	return conn, int(atomic.AddUint32(&l.connections, 1)), nil
 }
 
 /*
@@ -149,10 +310,15 @@ func (l *Listener) WaitOnConnection() (net.Conn, int, error) {
 	Sends any error from the ConnHandler through the errPipe
 	Exits and returns any error received from the WaitOnConn
 	e.g. "Timedout" or "Connection not open"
+	On "Timedout" any IdleHandler is called first and serving
+	continues unless it asks to stop
 */
 func (l *Listener) HandleRequests(ch ConnHandler, errPipe chan<- error) error {
 	for {
 		conn, conNum, err := l.WaitOnConnection()
+		if nwk.Err_Timeout == err && nil != l.idle && !l.idle(l) {
+			continue
+		}
 		if err != nil {
 			return err
 		}
@@ -180,22 +346,94 @@ func (l *Listener) HandleARequest(ch ConnHandler) error {
 
 // ------------------------------------------------------------------------- //
 
+func newListener(ipPort string, status chan<- string, events chan<- ListenerEvent) (*Listener, error) {
+	return newListenerConfig(ipPort, status, events, nil)
+}
+
+func newListenerConfig(ipPort string, status chan<- string, events chan<- ListenerEvent, cfg *ListenConfig) (*Listener, error) {
+	l := Listener{stats: newListenStats(), adm: newAdmission(), hostIP: ipPort, statPipe: status, evtPipe: events}
+
+	lc := net.ListenConfig{}
+	if nil != cfg {
+		lc.Control = cfg.control
+	}
+	listener, err := lc.Listen(context.Background(), "tcp", ipPort)
+	if ListenDbg.ChkErr(err) {
+		return nil, nwk.ChkNetErr(err)
+	}
+	tl := listener.(*net.TCPListener)
+	l.listener = tl
+	if nil != cfg {
+		if err = cfg.backlog(tl); ListenDbg.ChkErr(err) {
+			tl.Close()
+			return nil, nwk.ChkNetErr(err)
+		}
+	}
+	l.event(ListenerEvent{Kind: EvCreated})
+
+	return &l, nil
+}
+
 func (l *Listener) handleConn(conn net.Conn, conNum int, ch ConnHandler, errPipe chan<- error) error {
-	serving := conn.RemoteAddr().String()
-	rw := NewReadWriter(conn)
-	l.status(fmt.Sprintf("Con%d@%s", conNum, serving))
-	err := nwk.ChkNetErr(ch(conNum, serving, rw))
-	conn.Close() // the rw is closed in-effect when the conn is closed
+	sc := conn.(*statConn)
+	serving := sc.remote
+	var rw ReadWriter
+	if l.bufWrite {
+		brw := newReadBufWriter(conn)
+		brw.flushOnRead = l.rdFlush
+		rw = brw
+	} else {
+		rw = NewReadWriter(conn)
+	}
+	rw.ReadTimeout(l.rTimeout)
+	rw.WriteTimeout(l.wTimeout)
+	sc.limit(l.idleTime, l.maxLife)
+	l.event(ListenerEvent{Kind: EvConnected, ConnNum: conNum, Remote: serving})
+	err := nwk.ChkNetErr(callHandler(ch, conNum, serving, rw))
+	if ferr := nwk.ChkNetErr(rw.Flush()); nil == err {
+		err = ferr // send anything still buffered
+	}
+	if reason := sc.closeReason(); nil != reason {
+		err = reason // the conn was closed out from under the handler
+	}
+	conn.Close() // the rw is closed in-effect when the conn is closed, updates the stats
+	l.stats.handlerErr(err)
 	if nil != err && nil != errPipe {
 		errPipe <- err
 	}
-	atomic.AddUint32(&l.servicing, ^uint32(0)) // -1 w/o error
-	l.status(fmt.Sprintf("Dis%d@%s(%v)", conNum, serving, err))
+	cs := sc.stats()
+	l.event(ListenerEvent{Kind: EvDisconnected, ConnNum: conNum, Remote: serving,
+		Duration: cs.Duration, BytesIn: cs.BytesIn, BytesOut: cs.BytesOut, Err: err})
+	if pe, ok := err.(*nwk.PanicError); ok && l.rePanic {
+		panic(pe) // keeps the stack, the original value is pe.Value
+	}
 	return err
 }
 
-func (l *Listener) status(s string) {
+// Call the ConnHandler, converting any panic to a *nwk.PanicError
+func callHandler(ch ConnHandler, conNum int, serving string, rw ReadWriter) (err error) {
+	defer func() {
+		if r := recover(); nil != r {
+			err = &nwk.PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return ch(conNum, serving, rw)
+}
+
+func (l *Listener) event(ev ListenerEvent) {
+	l.obsMu.Lock()
+	observer, observers := l.observer, l.observers
+	l.obsMu.Unlock()
+	if nil != observer {
+		observer(ev)
+	}
+	for _, fn := range observers {
+		fn(ev)
+	}
+	if nil != l.evtPipe {
+		l.evtPipe <- ev
+	}
 	if nil != l.statPipe {
-		l.statPipe <- s
+		l.statPipe <- ev.String()
 	}
 }
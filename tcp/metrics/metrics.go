@@ -0,0 +1,328 @@
+package metrics
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jayacarlson/nwk/tcp"
+)
+
+/*
+	Optional metrics for the tcp package, publishes the Listener and
+		client counters via expvar and/or a Prometheus text handler
+
+		NewExporter() *Exporter:
+			Create a new (empty) Exporter
+
+		Exporter.AddListener( name, *tcp.Listener ):
+			Add a listener to export, name is used as the "listener"
+			label -- adds a Listener observer (AddObserver) to collect
+			the connection duration histogram, any observer already
+			set is kept, and the Listener can already be serving
+			Adding the same Listener again adds another observer, the
+			histogram is then only kept by the last one
+
+		Exporter.ObserveClients():
+			Adds a dial observer (tcp.AddDialObserver) to collect the
+			client dial latency histogram, any tcp.DialObserver is kept,
+			the client counters are always exported
+
+		Exporter.Publish( name ):
+			Publishes the metrics as an expvar.Func under the given name
+
+		Exporter.ServeHTTP( http.ResponseWriter, *http.Request ):
+			Writes the metrics in the Prometheus text format, e.g.
+				http.Handle("/metrics", exporter)
+
+		Exporter.WriteTo( io.Writer ) ( int64, error ):
+			Writes the metrics in the Prometheus text format
+
+	Exported metrics, all listener metrics have a "listener" label:
+		nwk_tcp_servicing					gauge
+		nwk_tcp_servicing_peak				gauge
+		nwk_tcp_connections_total			counter
+		nwk_tcp_accept_errors_total			counter
+		nwk_tcp_rejected_total				counter, "error" label
+		nwk_tcp_bytes_in_total				counter
+		nwk_tcp_bytes_out_total				counter
+		nwk_tcp_handler_errors_total		counter, "error" label
+		nwk_tcp_connection_seconds			histogram
+		nwk_tcp_client_open					gauge
+		nwk_tcp_client_dials_total			counter
+		nwk_tcp_client_dial_errors_total	counter, "error" label
+		nwk_tcp_client_bytes_in_total		counter
+		nwk_tcp_client_bytes_out_total		counter
+		nwk_tcp_client_dial_seconds			histogram
+*/
+
+type (
+	Exporter struct {
+		mu        sync.Mutex
+		names     []string // listener names, in order added
+		listeners map[string]*tcp.Listener
+		connDur   map[string]*histogram // connection durations per listener
+		dialLat   *histogram            // client dial latency
+	}
+
+	histogram struct {
+		counts []uint64 // one per bucket, not cumulative
+		count  uint64
+		sum    float64
+	}
+)
+
+// histogram bucket upper bounds, in seconds
+var buckets = []float64{.001, .005, .01, .05, .1, .5, 1, 5, 10, 30, 60, 300}
+
+// Create a new Exporter
+func NewExporter() *Exporter {
+	return &Exporter{
+		listeners: map[string]*tcp.Listener{},
+		connDur:   map[string]*histogram{},
+		dialLat:   newHistogram(),
+	}
+}
+
+// ========================================================================= //
+
+// Add a listener to export, adds a Listener observer
+func (x *Exporter) AddListener(name string, l *tcp.Listener) {
+	x.mu.Lock()
+	if _, ok := x.listeners[name]; !ok {
+		x.names = append(x.names, name)
+	}
+	x.listeners[name] = l
+	h := newHistogram()
+	x.connDur[name] = h
+	x.mu.Unlock()
+
+	l.AddObserver(func(ev tcp.ListenerEvent) {
+		if tcp.EvDisconnected == ev.Kind {
+			x.mu.Lock()
+			h.observe(ev.Duration)
+			x.mu.Unlock()
+		}
+	})
+}
+
+// Collect the client dial latency, adds a tcp dial observer
+func (x *Exporter) ObserveClients() {
+	tcp.AddDialObserver(func(_ string, took time.Duration, _ error) {
+		x.mu.Lock()
+		x.dialLat.observe(took)
+		x.mu.Unlock()
+	})
+}
+
+// Publish the metrics via expvar
+func (x *Exporter) Publish(name string) {
+	expvar.Publish(name, expvar.Func(x.vars))
+}
+
+// Write the metrics in the Prometheus text format
+func (x *Exporter) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	x.WriteTo(w)
+}
+
+// Write the metrics in the Prometheus text format
+func (x *Exporter) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+
+	x.mu.Lock()
+	names := append([]string{}, x.names...)
+	x.mu.Unlock()
+	stats := make([]tcp.ListenerStats, len(names))
+	for i, n := range names {
+		stats[i] = x.listener(n).Stats()
+	}
+
+	series := func(name, help, typ string, val func(tcp.ListenerStats) interface{}) {
+		header(&b, name, help, typ)
+		for i, n := range names {
+			fmt.Fprintf(&b, "%s{listener=\"%s\"} %v\n", name, escape(n), val(stats[i]))
+		}
+	}
+
+	series("nwk_tcp_servicing", "Connections currently being serviced.", "gauge",
+		func(s tcp.ListenerStats) interface{} { return s.Servicing })
+	series("nwk_tcp_servicing_peak", "Most connections serviced at one time.", "gauge",
+		func(s tcp.ListenerStats) interface{} { return s.PeakServicing })
+	series("nwk_tcp_connections_total", "Connections accepted.", "counter",
+		func(s tcp.ListenerStats) interface{} { return s.Connections })
+	series("nwk_tcp_accept_errors_total", "Errors accepting connections.", "counter",
+		func(s tcp.ListenerStats) interface{} { return s.AcceptErrors })
+	series("nwk_tcp_bytes_in_total", "Bytes read from connections.", "counter",
+		func(s tcp.ListenerStats) interface{} { return s.BytesIn })
+	series("nwk_tcp_bytes_out_total", "Bytes written to connections.", "counter",
+		func(s tcp.ListenerStats) interface{} { return s.BytesOut })
+
+	header(&b, "nwk_tcp_rejected_total", "Connections rejected by admission control.", "counter")
+	for i, n := range names {
+		for _, e := range sortedErrs(stats[i].Rejected) {
+			fmt.Fprintf(&b, "nwk_tcp_rejected_total{listener=\"%s\",error=\"%s\"} %d\n",
+				escape(n), escape(e.Error()), stats[i].Rejected[e])
+		}
+	}
+
+	header(&b, "nwk_tcp_handler_errors_total", "Errors returned by connection handlers.", "counter")
+	for i, n := range names {
+		for _, e := range sortedErrs(stats[i].HandlerErrors) {
+			fmt.Fprintf(&b, "nwk_tcp_handler_errors_total{listener=\"%s\",error=\"%s\"} %d\n",
+				escape(n), escape(e.Error()), stats[i].HandlerErrors[e])
+		}
+	}
+
+	header(&b, "nwk_tcp_connection_seconds", "Connection durations.", "histogram")
+	x.mu.Lock()
+	for _, n := range names {
+		x.connDur[n].write(&b, "nwk_tcp_connection_seconds", fmt.Sprintf("listener=\"%s\",", escape(n)))
+	}
+	x.mu.Unlock()
+
+	cs := tcp.ClientCounters()
+	header(&b, "nwk_tcp_client_open", "Clients currently open.", "gauge")
+	fmt.Fprintf(&b, "nwk_tcp_client_open %d\n", cs.Open)
+	header(&b, "nwk_tcp_client_dials_total", "Client connection attempts.", "counter")
+	fmt.Fprintf(&b, "nwk_tcp_client_dials_total %d\n", cs.Dials)
+	header(&b, "nwk_tcp_client_dial_errors_total", "Failed client connection attempts.", "counter")
+	for _, e := range sortedErrs(cs.DialErrors) {
+		fmt.Fprintf(&b, "nwk_tcp_client_dial_errors_total{error=\"%s\"} %d\n", escape(e.Error()), cs.DialErrors[e])
+	}
+	header(&b, "nwk_tcp_client_bytes_in_total", "Bytes read by clients.", "counter")
+	fmt.Fprintf(&b, "nwk_tcp_client_bytes_in_total %d\n", cs.BytesIn)
+	header(&b, "nwk_tcp_client_bytes_out_total", "Bytes written by clients.", "counter")
+	fmt.Fprintf(&b, "nwk_tcp_client_bytes_out_total %d\n", cs.BytesOut)
+
+	header(&b, "nwk_tcp_client_dial_seconds", "Client dial latency.", "histogram")
+	x.mu.Lock()
+	x.dialLat.write(&b, "nwk_tcp_client_dial_seconds", "")
+	x.mu.Unlock()
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// ------------------------------------------------------------------------- //
+
+func (x *Exporter) listener(name string) *tcp.Listener {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.listeners[name]
+}
+
+// expvar data, a map of listener stats and the client counts
+func (x *Exporter) vars() interface{} {
+	x.mu.Lock()
+	names := append([]string{}, x.names...)
+	x.mu.Unlock()
+
+	ls := map[string]interface{}{}
+	for _, n := range names {
+		s := x.listener(n).Stats()
+		x.mu.Lock()
+		h := x.connDur[n].vars()
+		x.mu.Unlock()
+		ls[n] = map[string]interface{}{
+			"servicing":      s.Servicing,
+			"servicing_peak": s.PeakServicing,
+			"connections":    s.Connections,
+			"accept_errors":  s.AcceptErrors,
+			"rejected":       errMap(s.Rejected),
+			"bytes_in":       s.BytesIn,
+			"bytes_out":      s.BytesOut,
+			"handler_errors": errMap(s.HandlerErrors),
+			"conn_seconds":   h,
+		}
+	}
+
+	cs := tcp.ClientCounters()
+	x.mu.Lock()
+	h := x.dialLat.vars()
+	x.mu.Unlock()
+	return map[string]interface{}{
+		"listeners": ls,
+		"clients": map[string]interface{}{
+			"open":         cs.Open,
+			"dials":        cs.Dials,
+			"dial_errors":  errMap(cs.DialErrors),
+			"bytes_in":     cs.BytesIn,
+			"bytes_out":    cs.BytesOut,
+			"dial_seconds": h,
+		},
+	}
+}
+
+func newHistogram() *histogram {
+	return &histogram{counts: make([]uint64, len(buckets))}
+}
+
+func (h *histogram) observe(d time.Duration) {
+	s := d.Seconds()
+	h.count++
+	h.sum += s
+	for i, b := range buckets {
+		if s <= b {
+			h.counts[i]++
+			break
+		}
+	}
+}
+
+// write the histogram lines, labels (if any) must end with a ','
+func (h *histogram) write(b *strings.Builder, name, labels string) {
+	var cum uint64
+	for i, le := range buckets {
+		cum += h.counts[i]
+		fmt.Fprintf(b, "%s_bucket{%sle=\"%g\"} %d\n", name, labels, le, cum)
+	}
+	fmt.Fprintf(b, "%s_bucket{%sle=\"+Inf\"} %d\n", name, labels, h.count)
+	labels = strings.TrimSuffix(labels, ",")
+	if "" != labels {
+		labels = "{" + labels + "}"
+	}
+	fmt.Fprintf(b, "%s_sum%s %g\n", name, labels, h.sum)
+	fmt.Fprintf(b, "%s_count%s %d\n", name, labels, h.count)
+}
+
+func (h *histogram) vars() map[string]interface{} {
+	bs := map[string]uint64{}
+	var cum uint64
+	for i, le := range buckets {
+		cum += h.counts[i]
+		bs[fmt.Sprintf("%g", le)] = cum
+	}
+	return map[string]interface{}{"buckets": bs, "count": h.count, "sum": h.sum}
+}
+
+func header(b *strings.Builder, name, help, typ string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// escape a Prometheus label value
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func sortedErrs(m map[error]int) []error {
+	errs := make([]error, 0, len(m))
+	for e := range m {
+		errs = append(errs, e)
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return errs
+}
+
+func errMap(m map[error]int) map[string]int {
+	r := make(map[string]int, len(m))
+	for e, n := range m {
+		r[e.Error()] = n
+	}
+	return r
+}
@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jayacarlson/nwk"
+	"github.com/jayacarlson/nwk/tcp"
+	"github.com/jayacarlson/tst"
+)
+
+var (
+	chk      = tst.Chk{}
+	loopback = "127.0.0.1:0"
+)
+
+func Test_Exporter(t *testing.T) {
+	tst.Testing("Metrics exporter", "", true)
+
+	chk.Reset()
+	l, err := tcp.NewListener(loopback, nil)
+	chk.Err(err, "Failed to create loopback listener", t.FailNow)
+	disconnects := int32(0)
+	l.SetObserver(func(ev tcp.ListenerEvent) {
+		if tcp.EvDisconnected == ev.Kind {
+			atomic.AddInt32(&disconnects, 1)
+		}
+	})
+	dials := int32(0)
+	tcp.DialObserver = func(string, time.Duration, error) { atomic.AddInt32(&dials, 1) }
+	defer func() { tcp.DialObserver = nil }()
+	x := NewExporter()
+	x.ObserveClients()
+	x.Publish("nwk_test")
+
+	go l.HandleRequests(func(cn int, serving string, rw tcp.ReadWriter) error {
+		r, err := rw.ReadString()
+		if nil == err {
+			err = rw.WriteString(r)
+		}
+		if nil == err {
+			err = nwk.Err_ClosedByUser
+		}
+		return err
+	}, nil)
+	x.AddListener("test", l) // while serving
+
+	for i := 0; i < 2; i++ {
+		crw, err := tcp.NewClient(l.Addr(), time.Second, false)
+		chk.Err(err, "Failed to create client", t.FailNow)
+		chk.Err(crw.WriteString("ping\n"))
+		_, err = crw.ReadString()
+		chk.Err(err, "ReadString failed: %v", err)
+		crw.Close()
+	}
+	time.Sleep(time.Millisecond * 100)
+
+	rec := httptest.NewRecorder()
+	x.ServeHTTP(rec, nil)
+	out := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE nwk_tcp_connections_total counter\n",
+		"nwk_tcp_connections_total{listener=\"test\"} 2\n",
+		"nwk_tcp_bytes_in_total{listener=\"test\"} 10\n",
+		"nwk_tcp_handler_errors_total{listener=\"test\",error=\"Closed by user\"} 2\n",
+		"nwk_tcp_connection_seconds_bucket{listener=\"test\",le=\"+Inf\"} 2\n",
+		"nwk_tcp_connection_seconds_count{listener=\"test\"} 2\n",
+		"nwk_tcp_client_dials_total 2\n",
+		"nwk_tcp_client_dial_seconds_count 2\n",
+	} {
+		chk.Tru(strings.Contains(out, want), "Missing metric: %s", want)
+	}
+
+	var vars map[string]interface{}
+	chk.Err(json.Unmarshal([]byte(expvar.Get("nwk_test").String()), &vars))
+	ls, _ := vars["listeners"].(map[string]interface{})
+	tl, _ := ls["test"].(map[string]interface{})
+	chk.Tru(float64(2) == tl["connections"], "expvar connections invalid")
+	chk.Tru(2 == atomic.LoadInt32(&disconnects), "Existing observer not kept")
+	chk.Tru(2 == atomic.LoadInt32(&dials), "Existing DialObserver not kept")
+
+	l.Close()
+	chk.ShowPassFail(t, "Prometheus & expvar output")
+}
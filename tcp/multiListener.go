@@ -0,0 +1,130 @@
+package tcp
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/jayacarlson/nwk"
+)
+
+/*
+	A listener bound to several addresses (e.g. loopback plus LAN, or
+		every address returned by nwk.FindMyIP4Addrs) that feeds a single
+		ConnHandler
+
+		NewMultiListener( ListenIPs, Status chan ) ( *MultiListener, error ):
+			Creates a Listener for each ip:port, all sharing one
+			connection counter, Stats and admission rules (Allow/Deny,
+			rate limits) -- status messages are the same as for
+			NewListener, one set per address
+			If any address fails, all are closed and the error returned
+
+		NewMultiEventListener( ListenIPs, Events chan ) ( *MultiListener, error ):
+			Same as NewMultiListener, but sends ListenerEvents
+
+		MultiListener.Listeners() []*Listener:
+			Returns the listeners, e.g. to set timeouts and other options
+
+		MultiListener.HandleRequests( ConnHandler, ErrPipe ) error:
+			Runs HandleRequests on every listener, returns when they
+			have all exited, with the first error received
+
+		MultiListener.Addrs() []string:
+			Returns the addresses the listeners are bound to
+
+		MultiListener.Close():
+			Closes all the listeners
+
+		MultiListener.Counts() ( serving, totalConnections int ):
+		MultiListener.Stats() ListenerStats:
+			Same as Listener.Counts / Stats, across all the addresses
+*/
+
+type (
+	MultiListener struct {
+		listeners []*Listener
+		stats     *listenStats // shared by all the listeners
+	}
+)
+
+// create listeners on all the addresses, sharing counters and stats
+func NewMultiListener(ipPorts []string, status chan<- string) (*MultiListener, error) {
+	return newMultiListener(ipPorts, status, nil)
+}
+
+// create listeners on all the addresses that report ListenerEvents
+func NewMultiEventListener(ipPorts []string, events chan<- ListenerEvent) (*MultiListener, error) {
+	return newMultiListener(ipPorts, nil, events)
+}
+
+// ========================================================================= //
+
+// Return the listeners
+func (m *MultiListener) Listeners() []*Listener {
+	return m.listeners
+}
+
+// Handle connection requests on all the listeners
+func (m *MultiListener) HandleRequests(ch ConnHandler, errPipe chan<- error) error {
+	var wg sync.WaitGroup
+	var once sync.Once
+	var first error
+
+	for _, l := range m.listeners {
+		wg.Add(1)
+		go func(l *Listener) {
+			defer wg.Done()
+			err := l.HandleRequests(ch, errPipe)
+			once.Do(func() { first = err })
+		}(l)
+	}
+	wg.Wait()
+	return first
+}
+
+// Return the actual addresses the listeners are bound to
+func (m *MultiListener) Addrs() []string {
+	addrs := make([]string, len(m.listeners))
+	for i, l := range m.listeners {
+		addrs[i] = l.Addr()
+	}
+	return addrs
+}
+
+// Close all the listeners
+func (m *MultiListener) Close() {
+	for _, l := range m.listeners {
+		l.Close()
+	}
+}
+
+// Return the current and total number of connections
+func (m *MultiListener) Counts() (servicing, totalConnections int) {
+	return int(atomic.LoadUint32(&m.stats.servicing)), int(atomic.LoadUint32(&m.stats.connections))
+}
+
+// Return a snapshot of the stats across all the listeners
+func (m *MultiListener) Stats() ListenerStats {
+	return m.stats.snapshot()
+}
+
+// ------------------------------------------------------------------------- //
+
+func newMultiListener(ipPorts []string, status chan<- string, events chan<- ListenerEvent) (*MultiListener, error) {
+	if 0 == len(ipPorts) {
+		return nil, nwk.Err_IllegalParam
+	}
+	m := MultiListener{stats: newListenStats()}
+	adm := newAdmission()
+
+	for _, ipPort := range ipPorts {
+		l, err := newListener(ipPort, status, events)
+		if nil != err {
+			m.Close()
+			return nil, err
+		}
+		l.stats, l.adm = m.stats, adm
+		m.listeners = append(m.listeners, l)
+	}
+	return &m, nil
+}
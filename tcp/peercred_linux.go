@@ -0,0 +1,31 @@
+//go:build linux
+// +build linux
+
+package tcp
+
+import (
+	"net"
+	"os"
+	"syscall"
+
+	"github.com/jayacarlson/nwk"
+)
+
+func peerCred(uc *net.UnixConn) (Ucred, error) {
+	rc, err := uc.SyscallConn()
+	if nil != err {
+		return Ucred{}, nwk.ChkNetErr(err)
+	}
+	var cred *syscall.Ucred
+	var serr error
+	err = rc.Control(func(fd uintptr) {
+		cred, serr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	})
+	if nil == err {
+		err = os.NewSyscallError("getsockopt SO_PEERCRED", serr)
+	}
+	if nil != err {
+		return Ucred{}, nwk.ChkNetErr(err)
+	}
+	return Ucred{Pid: int(cred.Pid), Uid: int(cred.Uid), Gid: int(cred.Gid)}, nil
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package tcp
+
+import (
+	"net"
+
+	"github.com/jayacarlson/nwk"
+)
+
+func peerCred(uc *net.UnixConn) (Ucred, error) {
+	return Ucred{}, nwk.Err_NotSupported
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/binary"
+	"io"
 	"net"
 	"time"
 
@@ -31,13 +32,24 @@ type (
 		srvrIP       string        // if we are a client, this is who we are connected to
 		conn         net.Conn      // writer goes direct to net.Conn
 		reader       *bufio.Reader // reading is always done buffered
-		eol          byte          // EOL byte for ReadBytes
+		eol          byte          // EOL byte for ReadBytes / ReadString
+		lineMode     LineMode      // how lines end
+		stripEOL     bool          // strip the line terminator
+		crPend       bool          // LineAny line ended in '\r', drop a following '\n'
+		frame        framing       // length prefix for ReadFrame / WriteFrame
+		scanLimit    int           // max bytes FindStart / ReadRecord scan for a marker
+		sum          Checksum      // record checksum trailer
+		sumOrd       binary.ByteOrder
+		maxLine      int           // longest line for ReadBytes / ReadString, 0 no limit
+		maxRecord    int           // largest record, 0 no limit
+		src          *budgetReader // counts (and limits) the bytes read from conn
 		readTimeout  time.Duration
 		writeTimeout time.Duration
 	}
 	readBufWriter struct {
-		r *readWriter   // reading is done through readWriter
-		w *bufio.Writer // writing is done buffered using readBufWriter
+		r           *readWriter   // reading is done through readWriter
+		w           *bufio.Writer // writing is done buffered using readBufWriter
+		flushOnRead bool          // flush any buffered writes before reading
 	}
 )
 
@@ -65,8 +77,38 @@ func (x *readWriter) Close() error {
 	return err
 }
 
+// Return the underlying net.Conn, without the wrapper keeping the stats
+func (x *readWriter) Conn() net.Conn {
+	return UnwrapConn(x.conn)
+}
+
+func (x *readWriter) LocalAddr() net.Addr {
+	return x.conn.LocalAddr()
+}
+
+func (x *readWriter) RemoteAddr() net.Addr {
+	return x.conn.RemoteAddr()
+}
+
+// Shut down the reading side of the conn, anything already buffered can
+//	still be read
+func (x *readWriter) CloseRead() error {
+	if c, ok := x.conn.(HalfCloser); ok {
+		return nwk.ChkNetErr(c.CloseRead())
+	}
+	return nwk.Err_NotSupported
+}
+
+// Shut down the writing side of the conn, the remote reads io.EOF
+func (x *readWriter) CloseWrite() error {
+	if c, ok := x.conn.(HalfCloser); ok {
+		return nwk.ChkNetErr(c.CloseWrite())
+	}
+	return nwk.Err_NotSupported
+}
+
 func (x *readWriter) SetEOL(eol byte) {
-	x.eol = eol
+	x.eol, x.lineMode = eol, LineEOL
 }
 
 func (x *readWriter) ReadTimeout(to time.Duration) {
@@ -77,21 +119,19 @@ func (x *readWriter) WriteTimeout(to time.Duration) {
 	x.writeTimeout = to
 }
 
+func (x *readWriter) SetScanLimit(limit int) {
+	x.scanLimit = limit
+}
+
 // ========================================================================= //
 
 func (x *readWriter) FindStart(stRec []byte) error {
 	x.setRExpiry()
-waitStart:
-	for i := 0; i < len(stRec); i++ {
-		b, err := x.ReadByte()
-		if nil != err {
-			return err
-		}
-		if b != stRec[i] {
-			goto waitStart
-		}
+	if 0 == len(stRec) {
+		return nil
 	}
-	return nil
+	_, err := newMatcher(stRec).scan(x.reader, x.scanLimit, nil)
+	return nwk.ChkNetErr(err)
 }
 
 func (x *readWriter) Read(buf []byte) (int, error) {
@@ -106,16 +146,36 @@ func (x *readWriter) ReadByte() (byte, error) {
 	return b, nwk.ChkNetErr(err)
 }
 
+func (x *readWriter) UnreadByte() error {
+	return x.reader.UnreadByte()
+}
+
+func (x *readWriter) Peek(n int) ([]byte, error) {
+	x.setRExpiry()
+	b, err := x.reader.Peek(n)
+	return b, nwk.ChkNetErr(err)
+}
+
+func (x *readWriter) Buffered() int {
+	return x.reader.Buffered()
+}
+
+func (x *readWriter) Discard(n int) (int, error) {
+	x.setRExpiry()
+	d, err := x.reader.Discard(n)
+	return d, nwk.ChkNetErr(err)
+}
+
 func (x *readWriter) ReadBytes() ([]byte, error) {
 	x.setRExpiry()
-	b, err := x.reader.ReadBytes(x.eol)
+	b, err := x.readLine()
 	return b, nwk.ChkNetErr(err)
 }
 
 func (x *readWriter) ReadString() (string, error) {
 	x.setRExpiry()
-	s, err := x.reader.ReadString('\n')
-	return s, nwk.ChkNetErr(err)
+	b, err := x.readLine()
+	return string(b), nwk.ChkNetErr(err)
 }
 
 func (x *readWriter) ReadRecord(stRec, enRec []byte) ([]byte, error) {
@@ -125,19 +185,19 @@ func (x *readWriter) ReadRecord(stRec, enRec []byte) ([]byte, error) {
 		return []byte{}, nwk.ChkNetErr(err)
 	}
 	data := []byte{}
-waitEnd:
-	for i := 0; i < len(enRec); i++ {
-		b, err := x.ReadByte()
-		if nil != err {
-			return data, nwk.ChkNetErr(err)
-		}
-		if b != enRec[i] {
-			data = append(data, enRec[:i]...)
-			data = append(data, b)
-			goto waitEnd
-		}
+	limit, limitErr := x.recordLimit(enRec)
+	_, err = newMatcher(enRec).scan(x.reader, limit, &data)
+	if nwk.Err_ScanLimit == err {
+		err = limitErr
+	}
+	if nwk.Err_TooLarge == err && len(data) > x.maxRecord {
+		data = data[:x.maxRecord]
 	}
-	return data, nil
+	if nil != err {
+		return data, nwk.ChkNetErr(err)
+	}
+	data = data[:len(data)-len(enRec)]
+	return data, x.checkSum(data)
 }
 
 func (x *readWriter) ReadSizedRecord(stRec []byte, recLen int) ([]byte, error) {
@@ -145,9 +205,18 @@ func (x *readWriter) ReadSizedRecord(stRec []byte, recLen int) ([]byte, error) {
 	if nil != err {
 		return []byte{}, nwk.ChkNetErr(err)
 	}
+	if 0 != x.maxRecord && recLen > x.maxRecord {
+		return []byte{}, nwk.Err_TooLarge
+	}
 	data := make([]byte, recLen)
-	l, err := x.Read(data)
-	return data[:l], nwk.ChkNetErr(err)
+	l, err := io.ReadFull(x.reader, data) // the FindStart expiry covers the whole record
+	if nil != err {
+		if 0 != len(stRec) {
+			err = noEOF(err) // closed after the start marker
+		}
+		return data[:l], nwk.ChkNetErr(err)
+	}
+	return data, x.checkSum(data)
 }
 
 func (x *readWriter) ReadStruct(ord binary.ByteOrder, i interface{}) error {
@@ -155,14 +224,15 @@ func (x *readWriter) ReadStruct(ord binary.ByteOrder, i interface{}) error {
 	if bsz == 0 {
 		return nil
 	}
+	if bsz < 0 {
+		return nwk.Err_IllegalParam // not a fixed size type
+	}
+	x.setRExpiry()
 	data := make([]byte, bsz)
-	rsz, err := x.Read(data)
+	_, err := io.ReadFull(x.reader, data)
 	if nil != err {
 		return nwk.ChkNetErr(err)
 	}
-	if rsz != bsz {
-		return nwk.Err_BadData
-	}
 	return binary.Read(bytes.NewBuffer(data), ord, i)
 }
 
@@ -171,9 +241,8 @@ func (x *readWriter) ReadStruct(ord binary.ByteOrder, i interface{}) error {
 func (x *readWriter) Flush() error { return nil }
 
 func (x *readWriter) Write(dta []byte) error {
-	x.setWExpiry()
-	_, err := x.conn.Write(dta)
-	return nwk.ChkNetErr(err)
+	_, err := x.writeN(dta)
+	return err
 }
 
 func (x *readWriter) WriteByte(byt byte) error {
@@ -201,7 +270,7 @@ func (x *readWriter) WriteStruct(ord binary.ByteOrder, i interface{}) error {
 // ========================================================================= //
 
 func (x *readBufWriter) Close() error {
-	ferr := x.w.Flush()
+	ferr := x.Flush()
 	cerr := x.r.Close()
 	if nil != ferr {
 		return ferr
@@ -209,8 +278,33 @@ func (x *readBufWriter) Close() error {
 	return cerr
 }
 
+func (x *readBufWriter) Conn() net.Conn {
+	return x.r.Conn()
+}
+
+func (x *readBufWriter) LocalAddr() net.Addr {
+	return x.r.LocalAddr()
+}
+
+func (x *readBufWriter) RemoteAddr() net.Addr {
+	return x.r.RemoteAddr()
+}
+
+func (x *readBufWriter) CloseRead() error {
+	return x.r.CloseRead()
+}
+
+// Flushes any buffered writes first
+func (x *readBufWriter) CloseWrite() error {
+	x.r.setWExpiry()
+	if err := x.w.Flush(); nil != err {
+		return nwk.ChkNetErr(err)
+	}
+	return x.r.CloseWrite()
+}
+
 func (x *readBufWriter) SetEOL(eol byte) {
-	x.r.eol = eol
+	x.r.SetEOL(eol)
 }
 
 func (x *readBufWriter) ReadTimeout(to time.Duration) {
@@ -221,31 +315,89 @@ func (x *readBufWriter) WriteTimeout(to time.Duration) {
 	x.r.writeTimeout = to
 }
 
+func (x *readBufWriter) SetScanLimit(limit int) {
+	x.r.scanLimit = limit
+}
+
 // ========================================================================= //
 
-func (x *readBufWriter) FindStart(stRec []byte) error { return x.r.FindStart(stRec) }
-func (x *readBufWriter) Read(buf []byte) (int, error) { return x.r.Read(buf) }
-func (x *readBufWriter) ReadByte() (byte, error)      { return x.r.ReadByte() }
-func (x *readBufWriter) ReadBytes() ([]byte, error)   { return x.r.ReadBytes() }
-func (x *readBufWriter) ReadString() (string, error)  { return x.r.ReadString() }
+func (x *readBufWriter) FindStart(stRec []byte) error {
+	if err := x.flushRead(); nil != err {
+		return err
+	}
+	return x.r.FindStart(stRec)
+}
+func (x *readBufWriter) Read(buf []byte) (int, error) {
+	if err := x.flushRead(); nil != err {
+		return 0, err
+	}
+	return x.r.Read(buf)
+}
+func (x *readBufWriter) ReadByte() (byte, error) {
+	if err := x.flushRead(); nil != err {
+		return 0, err
+	}
+	return x.r.ReadByte()
+}
+func (x *readBufWriter) UnreadByte() error {
+	return x.r.UnreadByte()
+}
+func (x *readBufWriter) Peek(n int) ([]byte, error) {
+	if err := x.flushRead(); nil != err {
+		return nil, err
+	}
+	return x.r.Peek(n)
+}
+func (x *readBufWriter) Buffered() int {
+	return x.r.Buffered()
+}
+func (x *readBufWriter) Discard(n int) (int, error) {
+	if err := x.flushRead(); nil != err {
+		return 0, err
+	}
+	return x.r.Discard(n)
+}
+func (x *readBufWriter) ReadBytes() ([]byte, error) {
+	if err := x.flushRead(); nil != err {
+		return nil, err
+	}
+	return x.r.ReadBytes()
+}
+func (x *readBufWriter) ReadString() (string, error) {
+	if err := x.flushRead(); nil != err {
+		return "", err
+	}
+	return x.r.ReadString()
+}
 func (x *readBufWriter) ReadRecord(stRec, enRec []byte) ([]byte, error) {
+	if err := x.flushRead(); nil != err {
+		return []byte{}, err
+	}
 	return x.r.ReadRecord(stRec, enRec)
 }
 func (x *readBufWriter) ReadSizedRecord(stRec []byte, recLen int) ([]byte, error) {
+	if err := x.flushRead(); nil != err {
+		return []byte{}, err
+	}
 	return x.r.ReadSizedRecord(stRec, recLen)
 }
 func (x *readBufWriter) ReadStruct(ord binary.ByteOrder, i interface{}) error {
+	if err := x.flushRead(); nil != err {
+		return err
+	}
 	return x.r.ReadStruct(ord, i)
 }
 
 // ========================================================================= //
 
-func (x *readBufWriter) Flush() error { return x.w.Flush() }
+func (x *readBufWriter) Flush() error {
+	x.r.setWExpiry() // the last write's deadline may have passed
+	return nwk.ChkNetErr(x.w.Flush())
+}
 
 func (x *readBufWriter) Write(dta []byte) error {
-	x.r.setWExpiry()
-	_, err := x.w.Write(dta)
-	return nwk.ChkNetErr(err)
+	_, err := x.writeN(dta)
+	return err
 }
 
 func (x *readBufWriter) WriteByte(byt byte) error {
@@ -275,9 +427,12 @@ func (x *readBufWriter) WriteStruct(ord binary.ByteOrder, i interface{}) error {
 func newReadWriter(conn net.Conn) *readWriter {
 	x := readWriter{
 		conn:   conn,
-		reader: bufio.NewReader(conn),
+		src:    &budgetReader{conn: conn},
 		eol:    '\n',
+		frame:  framing{prefix: FrameU32, ord: binary.BigEndian, max: DefaultMaxFrame},
+		sumOrd: binary.BigEndian,
 	}
+	x.reader = bufio.NewReader(x.src)
 	return &x
 }
 
@@ -304,3 +459,25 @@ func (x *readWriter) setWExpiry() {
 	}
 	x.conn.SetWriteDeadline(expiry)
 }
+
+// Write returning the count written, for Stream
+func (x *readWriter) writeN(dta []byte) (int, error) {
+	x.setWExpiry()
+	n, err := x.conn.Write(dta)
+	return n, nwk.ChkNetErr(err)
+}
+
+func (x *readBufWriter) writeN(dta []byte) (int, error) {
+	x.r.setWExpiry()
+	n, err := x.w.Write(dta)
+	return n, nwk.ChkNetErr(err)
+}
+
+// Flush any buffered writes before a read if flushOnRead is set
+func (x *readBufWriter) flushRead() error {
+	if !x.flushOnRead || 0 == x.w.Buffered() {
+		return nil
+	}
+	x.r.setWExpiry()
+	return nwk.ChkNetErr(x.w.Flush())
+}
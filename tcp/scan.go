@@ -0,0 +1,64 @@
+package tcp
+
+import (
+	"io"
+
+	"github.com/jayacarlson/nwk"
+)
+
+/*
+	Marker search for FindStart and ReadRecord, a Knuth-Morris-Pratt
+		matcher reading a byte at a time so nothing past the marker
+		is consumed, and a marker that overlaps itself (e.g. "aab"
+		in "aaab") is still found
+*/
+
+type (
+	matcher struct {
+		pat  []byte
+		fail []int // length of the longest proper prefix of pat[:i+1] that is also its suffix
+	}
+)
+
+func newMatcher(pat []byte) *matcher {
+	fail := make([]int, len(pat))
+	for i, k := 1, 0; i < len(pat); i++ {
+		for 0 < k && pat[i] != pat[k] {
+			k = fail[k-1]
+		}
+		if pat[i] == pat[k] {
+			k++
+		}
+		fail[i] = k
+	}
+	return &matcher{pat: pat, fail: fail}
+}
+
+// ------------------------------------------------------------------------- //
+
+// Read until the pattern is matched, returns the number of bytes read
+//	(including the pattern), appending them to keep if given
+//	nwk.Err_ScanLimit if limit (0 for none) bytes are read without a match
+func (m *matcher) scan(r io.ByteReader, limit int, keep *[]byte) (int, error) {
+	n, k := 0, 0
+	for k < len(m.pat) {
+		if 0 != limit && n >= limit {
+			return n, nwk.Err_ScanLimit
+		}
+		b, err := r.ReadByte()
+		if nil != err {
+			return n, err
+		}
+		n++
+		if nil != keep {
+			*keep = append(*keep, b)
+		}
+		for 0 < k && b != m.pat[k] {
+			k = m.fail[k-1]
+		}
+		if b == m.pat[k] {
+			k++
+		}
+	}
+	return n, nil
+}
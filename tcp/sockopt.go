@@ -0,0 +1,104 @@
+package tcp
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+/*
+	Socket options for a Listener, set on the socket before it is bound
+		so a server can be restarted quickly or several processes can
+		share the same port
+
+		NewListenerConfig( ListenIP, Status chan, ListenConfig ) ( *Listener, error ):
+			Same as NewListener, with the socket options in ListenConfig
+
+		NewEventListenerConfig( ListenIP, Events chan, ListenConfig ) ( *Listener, error ):
+			Same as NewEventListener, with the socket options in ListenConfig
+
+		ListenConfig:
+			ReuseAddr:	SO_REUSEADDR, bind while old connections on the
+						port are still in TIME_WAIT
+			ReusePort:	SO_REUSEPORT, several listeners (processes) bound
+						to the same ip:port, the kernel spreads the
+						connections between them
+			FreeBind:	IP_FREEBIND, bind to an address not (yet) assigned
+						to the host
+			FastOpen:	TCP_FASTOPEN queue length, 0 is off
+			Backlog:	length of the pending connection queue, 0 uses
+						the system default (net.core.somaxconn)
+			Control:	func called with the raw socket after the options
+						above are set, for any other options -- same as
+						net.ListenConfig.Control
+
+		All options are supported on linux, ReuseAddr and ReusePort also
+		on darwin and the BSDs -- any other option returns
+		nwk.Err_NotSupported (only Control is supported elsewhere)
+		Errors are mapped through nwk.ChkNetErr, e.g. nwk.Err_AddressInUse
+		when the port is in use and ReusePort isn't set on every listener
+*/
+
+type (
+	ListenConfig struct {
+		ReuseAddr bool
+		ReusePort bool
+		FreeBind  bool
+		FastOpen  int
+		Backlog   int
+		Control   func(network, address string, c syscall.RawConn) error
+	}
+)
+
+// create a TCP listener with the given socket options
+func NewListenerConfig(ipPort string, status chan<- string, cfg ListenConfig) (*Listener, error) {
+	return newListenerConfig(ipPort, status, nil, &cfg)
+}
+
+// create a TCP listener with the given socket options that reports ListenerEvents
+func NewEventListenerConfig(ipPort string, events chan<- ListenerEvent, cfg ListenConfig) (*Listener, error) {
+	return newListenerConfig(ipPort, nil, events, &cfg)
+}
+
+// ------------------------------------------------------------------------- //
+
+// net.ListenConfig.Control func, sets the options then calls any user Control
+func (cfg *ListenConfig) control(network, address string, c syscall.RawConn) error {
+	var serr error
+	err := c.Control(func(fd uintptr) {
+		serr = setSockOpts(fd, network, cfg)
+	})
+	if nil == err {
+		err = serr
+	}
+	if nil == err && nil != cfg.Control {
+		err = cfg.Control(network, address, c)
+	}
+	return err
+}
+
+// Change the backlog of a listening socket
+func (cfg *ListenConfig) backlog(l *net.TCPListener) error {
+	if 0 == cfg.Backlog {
+		return nil
+	}
+	rc, err := l.SyscallConn()
+	if nil != err {
+		return err
+	}
+	var serr error
+	err = rc.Control(func(fd uintptr) {
+		serr = setBacklog(fd, cfg.Backlog)
+	})
+	if nil == err {
+		err = serr
+	}
+	if nil != err {
+		return &net.OpError{Op: "listen", Net: "tcp", Addr: l.Addr(), Err: err}
+	}
+	return nil
+}
+
+func sockOptErr(name string, err error) error {
+	return os.NewSyscallError("setsockopt "+name, err)
+}
@@ -0,0 +1,32 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+// +build darwin dragonfly freebsd netbsd openbsd
+
+package tcp
+
+import (
+	"syscall"
+
+	"github.com/jayacarlson/nwk"
+)
+
+func setSockOpts(fd uintptr, network string, cfg *ListenConfig) error {
+	if cfg.FreeBind || 0 != cfg.FastOpen {
+		return nwk.Err_NotSupported
+	}
+	s := int(fd)
+	if cfg.ReuseAddr {
+		if err := syscall.SetsockoptInt(s, syscall.SOL_SOCKET, syscall.SO_REUSEADDR, 1); nil != err {
+			return sockOptErr("SO_REUSEADDR", err)
+		}
+	}
+	if cfg.ReusePort {
+		if err := syscall.SetsockoptInt(s, syscall.SOL_SOCKET, syscall.SO_REUSEPORT, 1); nil != err {
+			return sockOptErr("SO_REUSEPORT", err)
+		}
+	}
+	return nil
+}
+
+func setBacklog(fd uintptr, n int) error {
+	return nwk.Err_NotSupported
+}
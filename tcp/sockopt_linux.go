@@ -0,0 +1,49 @@
+//go:build linux
+// +build linux
+
+package tcp
+
+import (
+	"os"
+	"syscall"
+)
+
+const (
+	soReusePort  = 0xf  // SO_REUSEPORT, missing from syscall
+	tcpFastOpen  = 0x17 // TCP_FASTOPEN
+	ipv6FreeBind = 0x4e // IPV6_FREEBIND
+)
+
+func setSockOpts(fd uintptr, network string, cfg *ListenConfig) error {
+	s := int(fd)
+	if cfg.ReuseAddr {
+		if err := syscall.SetsockoptInt(s, syscall.SOL_SOCKET, syscall.SO_REUSEADDR, 1); nil != err {
+			return sockOptErr("SO_REUSEADDR", err)
+		}
+	}
+	if cfg.ReusePort {
+		if err := syscall.SetsockoptInt(s, syscall.SOL_SOCKET, soReusePort, 1); nil != err {
+			return sockOptErr("SO_REUSEPORT", err)
+		}
+	}
+	if cfg.FreeBind {
+		level, opt := syscall.IPPROTO_IP, syscall.IP_FREEBIND
+		if "tcp6" == network {
+			level, opt = syscall.IPPROTO_IPV6, ipv6FreeBind
+		}
+		if err := syscall.SetsockoptInt(s, level, opt, 1); nil != err {
+			return sockOptErr("IP_FREEBIND", err)
+		}
+	}
+	if 0 != cfg.FastOpen {
+		if err := syscall.SetsockoptInt(s, syscall.IPPROTO_TCP, tcpFastOpen, cfg.FastOpen); nil != err {
+			return sockOptErr("TCP_FASTOPEN", err)
+		}
+	}
+	return nil
+}
+
+// linux allows listen to be called again to change the backlog
+func setBacklog(fd uintptr, n int) error {
+	return os.NewSyscallError("listen", syscall.Listen(int(fd), n))
+}
@@ -0,0 +1,19 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd
+
+package tcp
+
+import (
+	"github.com/jayacarlson/nwk"
+)
+
+func setSockOpts(fd uintptr, network string, cfg *ListenConfig) error {
+	if cfg.ReuseAddr || cfg.ReusePort || cfg.FreeBind || 0 != cfg.FastOpen {
+		return nwk.Err_NotSupported
+	}
+	return nil
+}
+
+func setBacklog(fd uintptr, n int) error {
+	return nwk.Err_NotSupported
+}
@@ -0,0 +1,164 @@
+package tcp
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jayacarlson/nwk"
+)
+
+/*
+	Connection statistics kept by a Listener
+
+		Listener.Stats() ListenerStats:
+			Returns a snapshot of the listener statistics, safe to
+			call at any time from any GO ROUTINE
+
+		ListenerStats:
+			Servicing, Connections:	same as returned by Counts()
+			PeakServicing:	most connections serviced at one time
+			AcceptErrors:	errors from Accept, other than timeouts
+							or closing the listener
+			Rejected:		connections rejected by admission control,
+							keyed by the reason (see admission.go)
+			BytesIn, BytesOut:	total bytes read from / written to all
+							connections, including the active ones
+			ConnTime:		total time of all closed connections
+			HandlerErrors:	count of errors returned by ConnHandlers,
+							keyed by the nwk.ErrClass of the error
+			Active:			ConnStats of each open connection
+
+		ConnStats:
+			Per connection statistics, bytes are counted as they pass
+			through the net.Conn (and so the ReadWriter)
+*/
+
+type (
+	ConnStats struct {
+		ConnNum  int
+		Remote   string
+		Start    time.Time
+		Duration time.Duration
+		BytesIn  uint64
+		BytesOut uint64
+	}
+
+	ListenerStats struct {
+		Servicing     int
+		Connections   int
+		PeakServicing int
+		AcceptErrors  int
+		Rejected      map[error]int
+		BytesIn       uint64
+		BytesOut      uint64
+		ConnTime      time.Duration
+		HandlerErrors map[error]int
+		Active        []ConnStats
+	}
+
+	listenStats struct {
+		connections uint32 // total number of connections made
+		servicing   uint32 // number of active connections, updated when the conn is closed
+
+		mu          sync.Mutex
+		peak        int
+		acceptErrs  int
+		rejects     map[error]int
+		bytesIn     uint64 // totals from closed connections
+		bytesOut    uint64
+		connTime    time.Duration
+		handlerErrs map[error]int
+		active      map[int]*statConn
+	}
+)
+
+func newListenStats() *listenStats {
+	return &listenStats{handlerErrs: map[error]int{}, rejects: map[error]int{}, active: map[int]*statConn{}}
+}
+
+// ========================================================================= //
+
+// Return a snapshot of the listener statistics
+func (l *Listener) Stats() ListenerStats {
+	return l.stats.snapshot()
+}
+
+// ------------------------------------------------------------------------- //
+
+func (s *listenStats) snapshot() ListenerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ls := ListenerStats{
+		Servicing:     int(atomic.LoadUint32(&s.servicing)),
+		Connections:   int(atomic.LoadUint32(&s.connections)),
+		PeakServicing: s.peak,
+		AcceptErrors:  s.acceptErrs,
+		Rejected:      make(map[error]int, len(s.rejects)),
+		BytesIn:       s.bytesIn,
+		BytesOut:      s.bytesOut,
+		ConnTime:      s.connTime,
+		HandlerErrors: make(map[error]int, len(s.handlerErrs)),
+		Active:        make([]ConnStats, 0, len(s.active)),
+	}
+	for e, n := range s.handlerErrs {
+		ls.HandlerErrors[e] = n
+	}
+	for e, n := range s.rejects {
+		ls.Rejected[e] = n
+	}
+	for _, c := range s.active {
+		cs := c.stats()
+		ls.BytesIn += cs.BytesIn
+		ls.BytesOut += cs.BytesOut
+		ls.Active = append(ls.Active, cs)
+	}
+	sort.Slice(ls.Active, func(i, j int) bool { return ls.Active[i].ConnNum < ls.Active[j].ConnNum })
+	return ls
+}
+
+// Count a new connection, returns its connection number
+func (s *listenStats) opened(c *statConn) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c.connNum = int(atomic.AddUint32(&s.connections, 1))
+	if n := int(atomic.AddUint32(&s.servicing, 1)); n > s.peak {
+		s.peak = n
+	}
+	s.active[c.connNum] = c
+	return c.connNum
+}
+
+// Fold the counts of a closed connection into the totals
+func (s *listenStats) closed(c *statConn) {
+	cs := c.stats()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.active, c.connNum)
+	atomic.AddUint32(&s.servicing, ^uint32(0)) // -1 w/o error
+	s.bytesIn += cs.BytesIn
+	s.bytesOut += cs.BytesOut
+	s.connTime += cs.Duration
+}
+
+func (s *listenStats) acceptErr() {
+	s.mu.Lock()
+	s.acceptErrs++
+	s.mu.Unlock()
+}
+
+func (s *listenStats) rejected(reason error) {
+	s.mu.Lock()
+	s.rejects[reason]++
+	s.mu.Unlock()
+}
+
+func (s *listenStats) handlerErr(err error) {
+	if nil == err {
+		return
+	}
+	s.mu.Lock()
+	s.handlerErrs[nwk.ErrClass(err)]++
+	s.mu.Unlock()
+}
@@ -0,0 +1,145 @@
+package tcp
+
+import (
+	"io"
+
+	"github.com/jayacarlson/nwk"
+)
+
+/*
+	A ReadWriter's Write methods only return an error, so it can't be
+		used where an io.Writer is wanted (io.Copy, json.NewEncoder,
+		gzip.NewWriter...) -- Stream wraps a ReadWriter with the
+		standard io signatures
+
+		NewStream( ReadWriter ) *Stream:
+			Stream implements io.Reader, io.Writer, io.ByteScanner,
+			io.ByteWriter, io.StringWriter, io.ReaderFrom, io.WriterTo
+			and io.Closer, all going through the ReadWriter so the read
+			and write timeouts, limits and nwk error classification
+			still apply -- io.EOF is returned as is
+			UnreadByte needs a Peeker ReadWriter, as this package's,
+			nwk.Err_NotSupported otherwise
+			For a buffered ReadWriter use Flush (or Close) to send the
+			writes
+
+		Stream.ReadFrom( io.Reader ) ( int64, error ):
+			Writes everything read from the io.Reader until io.EOF,
+			the write timeout applies to each write
+
+		Stream.WriteTo( io.Writer ) ( int64, error ):
+			Writes everything read from the conn to the io.Writer until
+			the conn closes, the read timeout applies to each read --
+			returns nil at io.EOF
+*/
+
+type (
+	Stream struct {
+		rw ReadWriter
+	}
+
+	// Write returning the count written, as io.Writer
+	countWriter interface {
+		writeN(dta []byte) (int, error)
+	}
+)
+
+const streamBufSize = 32 * 1024
+
+func NewStream(rw ReadWriter) *Stream {
+	return &Stream{rw: rw}
+}
+
+// ========================================================================= //
+
+// Return the ReadWriter the Stream wraps
+func (s *Stream) ReadWriter() ReadWriter {
+	return s.rw
+}
+
+func (s *Stream) Read(buf []byte) (int, error) {
+	return s.rw.Read(buf)
+}
+
+func (s *Stream) ReadByte() (byte, error) {
+	return s.rw.ReadByte()
+}
+
+// nwk.Err_NotSupported if the ReadWriter isn't a Peeker
+func (s *Stream) UnreadByte() error {
+	if p, ok := s.rw.(Peeker); ok {
+		return p.UnreadByte()
+	}
+	return nwk.Err_NotSupported
+}
+
+func (s *Stream) Write(dta []byte) (int, error) {
+	if w, ok := s.rw.(countWriter); ok {
+		return w.writeN(dta)
+	}
+	if err := s.rw.Write(dta); nil != err {
+		return 0, err
+	}
+	return len(dta), nil
+}
+
+func (s *Stream) WriteByte(byt byte) error {
+	return s.rw.WriteByte(byt)
+}
+
+func (s *Stream) WriteString(str string) (int, error) {
+	return s.Write([]byte(str))
+}
+
+func (s *Stream) ReadFrom(r io.Reader) (int64, error) {
+	var total int64
+	buf := make([]byte, streamBufSize)
+	for {
+		n, rerr := r.Read(buf)
+		if 0 < n {
+			w, err := s.Write(buf[:n])
+			total += int64(w)
+			if nil != err {
+				return total, err
+			}
+		}
+		if io.EOF == rerr {
+			return total, nil
+		}
+		if nil != rerr {
+			return total, rerr
+		}
+	}
+}
+
+func (s *Stream) WriteTo(w io.Writer) (int64, error) {
+	var total int64
+	buf := make([]byte, streamBufSize)
+	for {
+		n, rerr := s.rw.Read(buf)
+		if 0 < n {
+			m, err := w.Write(buf[:n])
+			total += int64(m)
+			if nil == err && m < n {
+				err = io.ErrShortWrite
+			}
+			if nil != err {
+				return total, err
+			}
+		}
+		if io.EOF == rerr {
+			return total, nil
+		}
+		if nil != rerr {
+			return total, rerr
+		}
+	}
+}
+
+func (s *Stream) Flush() error {
+	return s.rw.Flush()
+}
+
+func (s *Stream) Close() error {
+	return s.rw.Close()
+}
@@ -0,0 +1,227 @@
+package tcp
+
+import (
+	"github.com/jayacarlson/nwk"
+)
+
+/*
+	Delimited records using byte stuffing, so the data can hold any
+		byte value including the delimiter -- e.g. for serial links
+		bridged over TCP
+
+		Stuffing modes:
+			StuffSLIP:	SLIP (RFC 1055), records end with END (0xC0),
+						END & ESC (0xDB) in the data are escaped
+			StuffCOBS:	Consistent Overhead Byte Stuffing, the data is
+						encoded without any 0x00, records end with 0x00
+			StuffHDLC:	HDLC-style (as PPP), records are between flags
+						(0x7E), flags & escapes (0x7D) in the data are
+						escaped as 0x7D, byte^0x20
+
+		Stuffer.ReadStuffed( Stuffing ) ( []byte, error ):
+			Reads the next record and returns the decoded data, empty
+			records (repeated delimiters) are skipped
+			nwk.Err_BadData if the record is not validly encoded
+			io.ErrUnexpectedEOF if the conn closes within a record
+
+		Stuffer.WriteStuffed( Stuffing, []byte ) error:
+			Encodes and writes the data as a single record, SLIP
+			and HDLC records also start with the delimiter to flush
+			any line noise at the receiver
+*/
+
+type (
+	Stuffing int
+)
+
+const (
+	StuffSLIP Stuffing = iota
+	StuffCOBS
+	StuffHDLC
+)
+
+const (
+	slipEnd    = 0xC0
+	slipEsc    = 0xDB
+	slipEscEnd = 0xDC
+	slipEscEsc = 0xDD
+	hdlcFlag   = 0x7E
+	hdlcEsc    = 0x7D
+	hdlcXor    = 0x20
+)
+
+// ========================================================================= //
+
+func (x *readWriter) ReadStuffed(mode Stuffing) ([]byte, error) {
+	delim, ok := stuffDelim(mode)
+	if !ok {
+		return nil, nwk.Err_IllegalParam
+	}
+	x.setRExpiry()
+	for {
+		raw, err := x.readDelim(delim, x.maxRecord)
+		if nil != err {
+			if 0 != len(raw) && nwk.Err_TooLarge != err {
+				err = noEOF(err)
+			}
+			return nil, nwk.ChkNetErr(err)
+		}
+		if 1 == len(raw) {
+			continue // empty record
+		}
+		return unstuff(mode, raw[:len(raw)-1])
+	}
+}
+
+func (x *readWriter) WriteStuffed(mode Stuffing, dta []byte) error {
+	rec, err := stuff(mode, dta)
+	if nil != err {
+		return err
+	}
+	return x.Write(rec)
+}
+
+// ========================================================================= //
+
+func (x *readBufWriter) ReadStuffed(mode Stuffing) ([]byte, error) {
+	if err := x.flushRead(); nil != err {
+		return nil, err
+	}
+	return x.r.ReadStuffed(mode)
+}
+
+func (x *readBufWriter) WriteStuffed(mode Stuffing, dta []byte) error {
+	rec, err := stuff(mode, dta)
+	if nil != err {
+		return err
+	}
+	return x.Write(rec)
+}
+
+// ------------------------------------------------------------------------- //
+
+func stuffDelim(mode Stuffing) (byte, bool) {
+	switch mode {
+	case StuffSLIP:
+		return slipEnd, true
+	case StuffCOBS:
+		return 0, true
+	case StuffHDLC:
+		return hdlcFlag, true
+	}
+	return 0, false
+}
+
+// Encode the data as a record, including the delimiter(s)
+func stuff(mode Stuffing, dta []byte) ([]byte, error) {
+	switch mode {
+	case StuffSLIP:
+		rec := make([]byte, 1, len(dta)+len(dta)/8+2)
+		rec[0] = slipEnd
+		for _, b := range dta {
+			switch b {
+			case slipEnd:
+				rec = append(rec, slipEsc, slipEscEnd)
+			case slipEsc:
+				rec = append(rec, slipEsc, slipEscEsc)
+			default:
+				rec = append(rec, b)
+			}
+		}
+		return append(rec, slipEnd), nil
+	case StuffCOBS:
+		return append(cobsEncode(dta), 0), nil
+	case StuffHDLC:
+		rec := make([]byte, 1, len(dta)+len(dta)/8+2)
+		rec[0] = hdlcFlag
+		for _, b := range dta {
+			if hdlcFlag == b || hdlcEsc == b {
+				rec = append(rec, hdlcEsc, b^hdlcXor)
+			} else {
+				rec = append(rec, b)
+			}
+		}
+		return append(rec, hdlcFlag), nil
+	}
+	return nil, nwk.Err_IllegalParam
+}
+
+// Decode a record, without the delimiter
+func unstuff(mode Stuffing, rec []byte) ([]byte, error) {
+	switch mode {
+	case StuffSLIP:
+		dta := make([]byte, 0, len(rec))
+		for i := 0; i < len(rec); i++ {
+			if slipEsc != rec[i] {
+				dta = append(dta, rec[i])
+				continue
+			}
+			if i++; i == len(rec) {
+				return nil, nwk.Err_BadData
+			}
+			switch rec[i] {
+			case slipEscEnd:
+				dta = append(dta, slipEnd)
+			case slipEscEsc:
+				dta = append(dta, slipEsc)
+			default:
+				return nil, nwk.Err_BadData
+			}
+		}
+		return dta, nil
+	case StuffCOBS:
+		return cobsDecode(rec)
+	case StuffHDLC:
+		dta := make([]byte, 0, len(rec))
+		for i := 0; i < len(rec); i++ {
+			if hdlcEsc != rec[i] {
+				dta = append(dta, rec[i])
+				continue
+			}
+			if i++; i == len(rec) {
+				return nil, nwk.Err_BadData
+			}
+			dta = append(dta, rec[i]^hdlcXor)
+		}
+		return dta, nil
+	}
+	return nil, nwk.Err_IllegalParam
+}
+
+// COBS encode, the result has no 0x00 bytes (and no delimiter)
+func cobsEncode(dta []byte) []byte {
+	enc := make([]byte, 1, len(dta)+len(dta)/254+2)
+	ci, code := 0, byte(1) // index of the current code byte, and its value
+	for i, b := range dta {
+		if 0 == b {
+			enc[ci] = code
+			ci, code = len(enc), 1
+			enc = append(enc, 0)
+			continue
+		}
+		enc = append(enc, b)
+		if code++; 0xFF == code && i < len(dta)-1 {
+			enc[ci] = code
+			ci, code = len(enc), 1
+			enc = append(enc, 0)
+		}
+	}
+	enc[ci] = code
+	return enc
+}
+
+// COBS decode, without the delimiter
+func cobsDecode(enc []byte) ([]byte, error) {
+	dta := make([]byte, 0, len(enc))
+	for i := 0; i < len(enc); {
+		code := int(enc[i])
+		if 0 == code || i+code > len(enc) {
+			return nil, nwk.Err_BadData
+		}
+		dta = append(dta, enc[i+1:i+code]...)
+		if i += code; 0xFF != code && i < len(enc) {
+			dta = append(dta, 0)
+		}
+	}
+	return dta, nil
+}
@@ -2,6 +2,7 @@ package tcp
 
 import (
 	"encoding/binary"
+	"net"
 	"time"
 )
 
@@ -44,7 +45,7 @@ import (
 			Closes the network connection
 
 		ReadWriter.SetEOL( byte ):
-			Set the EOL byte for ReadBytes, defaults to '\n' - same as ReadString
+			Set the EOL byte for ReadBytes and ReadString, defaults to '\n'
 
 		ReadWriter.ReadTimeout( time.Duration )
 			Sets the timeout duration for read calls -- 0 is no expiry
@@ -54,6 +55,8 @@ import (
 
 		ReadWriter.FindStart( []byte ) error:
 			Reads data until given stRec is matched, data read is discarded
+			The read timeout covers the whole search (and for ReadRecord
+			and ReadSizedRecord, reading the rest of the record)
 
 		ReadWriter.Read( []byte ) ( int, error ):
 			Reads data until given 'buf' is full or an error is received (EOF)
@@ -62,10 +65,11 @@ import (
 			Read a single byte or error received
 
 		ReadWriter.ReadBytes() ( []byte, error ):
-			Reads data until the EOL byte -- defaults to '\n', change with SetEOL
+			Reads data until the end of line -- the EOL byte, defaults to '\n',
+			change with SetEOL or Liner.SetLineMode
 
 		ReadWriter.ReadString() ( string, error ):
-			Reads data until '\n' byte and returns it as a string
+			Reads data as ReadBytes and returns it as a string
 
 		ReadWriter.ReadRecord( []byte, []byte ) ( []byte, error ):
 			Reads a variable sized 'record' of data.  Data can have an optional
@@ -75,11 +79,15 @@ import (
 		ReadWriter.ReadSizedRecord( []byte, int ) ( []byte, error ):
 			Reads a fixed sized 'record' of data.  Data can have an optional
 			starting sequence to signal start of data.  Data until stRec
-			(if given) is discarded.
+			(if given) is discarded.  Waits until all recLen bytes arrive
+			(or the read timeout expires), if the connection closes after
+			stRec or part way through the data read is returned with
+			io.ErrUnexpectedEOF
 
 		ReadWriter.ReadStruct( binary.ByteOrder, interface{} ) error:
 			Reads data assumed to be of the given ByteOrder
 			and use it to fill the interface
+			Waits until all the data arrives, as ReadSizedRecord
 
 		ReadWriter.Write( []byte ) error:
 			Writes a slice of bytes
@@ -92,6 +100,73 @@ import (
 
 		ReadWriter.WriteStruct( binary.ByteOrder, interface{} ) error:
 			Encodes an interface of the given ByteOrder and sends it
+
+	Optional interfaces -- the ReadWriters from this package (NewReadWriter,
+		NewReadBufWriter, the clients and those given to a ConnHandler)
+		implement them all, but they aren't part of ReadWriter so it stays
+		simple to implement or mock, type assert for them, e.g.
+			if f, ok := rw.(tcp.Framer); ok {
+				dta, err = f.ReadFrame()
+			}
+
+		ConnInfo.Conn() net.Conn:
+			Returns the underlying net.Conn, e.g. a *net.TCPConn for
+			SetKeepAlive or SetNoDelay -- reads and writes should still go
+			through the ReadWriter, the conn doesn't see data already
+			buffered and the Listener / client stats aren't updated
+
+		ConnInfo.LocalAddr() net.Addr / RemoteAddr() net.Addr:
+			Return the local and remote addresses of the conn
+
+		HalfCloser.CloseRead() error / CloseWrite() error:
+			Half close the conn (TCP and unix), e.g. CloseWrite to signal
+			the end of a request while still reading the response --
+			the remote reads io.EOF
+			A buffered ReadWriter flushes before CloseWrite
+			nwk.Err_NotSupported if the conn can't be half closed
+
+		Peeker.UnreadByte() error:
+			Unreads the last byte read by ReadByte (or the last byte of
+			the last read)
+
+		Peeker.Peek( int ) ( []byte, error ):
+			Returns the next n bytes without reading them, waiting for
+			them to arrive (within the read timeout) -- e.g. to choose
+			between a text or binary protocol on the first bytes
+			The slice is only valid until the next read
+			bufio.ErrBufferFull if n is larger than the read buffer
+
+		Peeker.Buffered() int:
+			Returns the number of bytes that can be read without
+			reading from the conn
+
+		Peeker.Discard( int ) ( int, error ):
+			Skips the next n bytes, returning the number skipped
+
+		Limiter.SetScanLimit( int ):
+			Sets the most bytes FindStart, and ReadRecord for each of its
+			markers, will read looking for the marker -- 0 is no limit
+			Past the limit they return nwk.Err_ScanLimit
+
+		Limiter.SetMaxLine / SetMaxRecord / SetReadBudget / BytesRead:
+			Limits on the size of lines, records and the total read,
+			returning nwk.Err_TooLarge (see limits.go)
+
+		Framer.SetFraming / ReadFrame / ReadFrameInto / WriteFrame:
+			Length-prefixed frames for binary data (see frame.go)
+
+		Stuffer.ReadStuffed / WriteStuffed:
+			SLIP, COBS and HDLC byte stuffed records (see stuffing.go)
+
+		Checksummer.SetChecksum / WriteRecord / WriteSizedRecord:
+			Records with a checksum trailer (see checksum.go)
+
+		Liner.SetLineMode / WriteLine:
+			CRLF, CR or any line endings, and terminator stripping (see lines.go)
+
+	NewStream( ReadWriter ) *Stream:
+		Wraps a ReadWriter as an io.Reader, io.Writer, io.ReaderFrom,
+		io.WriterTo etc., for io.Copy, encoders... (see stream.go)
 */
 
 type (
@@ -117,4 +192,56 @@ type (
 		WriteString(str string) error
 		WriteStruct(ord binary.ByteOrder, i interface{}) error
 	}
+
+	// Optional ReadWriter interfaces, see above
+
+	ConnInfo interface {
+		Conn() net.Conn
+		LocalAddr() net.Addr
+		RemoteAddr() net.Addr
+	}
+
+	// Also implemented by *net.TCPConn and *net.UnixConn
+	HalfCloser interface {
+		CloseRead() error
+		CloseWrite() error
+	}
+
+	Peeker interface {
+		UnreadByte() error
+		Peek(n int) ([]byte, error)
+		Buffered() int
+		Discard(n int) (int, error)
+	}
+
+	Limiter interface {
+		SetScanLimit(limit int)
+		SetMaxLine(max int)
+		SetMaxRecord(max int)
+		SetReadBudget(budget int64)
+		BytesRead() int64
+	}
+
+	Framer interface {
+		SetFraming(prefix FramePrefix, ord binary.ByteOrder, maxSize int)
+		ReadFrame() ([]byte, error)
+		ReadFrameInto(buf []byte) (int, error)
+		WriteFrame(dta []byte) error
+	}
+
+	Stuffer interface {
+		ReadStuffed(mode Stuffing) ([]byte, error)
+		WriteStuffed(mode Stuffing, dta []byte) error
+	}
+
+	Checksummer interface {
+		SetChecksum(sum Checksum, ord binary.ByteOrder)
+		WriteRecord(stRec, enRec, dta []byte) error
+		WriteSizedRecord(stRec, dta []byte) error
+	}
+
+	Liner interface {
+		SetLineMode(mode LineMode, strip bool)
+		WriteLine(s string) error
+	}
 )
@@ -1,12 +1,19 @@
 package tcp
 
 import (
+	"bytes"
 	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math/rand"
+	"net"
 	"os"
+	"os/exec"
 	"os/signal"
+	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
 	"syscall"
@@ -29,7 +36,7 @@ var (
 	cerrPipe  = make(chan error)        // client error pipe
 	ticker    = time.NewTicker(time.Hour)
 
-	loopback   = "127.0.0.1:1234"
+	loopback   = "127.0.0.1:0" // ephemeral port, clients connect to l.Addr()
 	piperQuiet = false
 
 	enableAll = true
@@ -43,6 +50,27 @@ var (
 	clientWriteTimeout  = (enableAll || false)
 	multipleWriteConns  = (enableAll || false)
 	testRecords         = (enableAll || false)
+	listenerEvents      = (enableAll || false)
+	listenerStats       = (enableAll || false)
+	idleHandler         = (enableAll || false)
+	connDeadlines       = (enableAll || false)
+	bufferedHandler     = (enableAll || false)
+	handlerPanics       = (enableAll || false)
+	admissionControl    = (enableAll || false)
+	multiListener       = (enableAll || false)
+	socketOptions       = (enableAll || false)
+	listenerHandoff     = (enableAll || false)
+	unixSockets         = (enableAll || false)
+	frameTests          = (enableAll || false)
+	fragmentedReads     = (enableAll || false)
+	stuffedRecords      = (enableAll || false)
+	markerScan          = (enableAll || false)
+	recordChecksums     = (enableAll || false)
+	readLimits          = (enableAll || false)
+	lineModes           = (enableAll || false)
+	ioStreams           = (enableAll || false)
+	halfClose           = (enableAll || false)
+	peekSniff           = (enableAll || false)
 )
 
 func pipeReader() {
@@ -181,18 +209,23 @@ func Test_SimpleOpenClose(t *testing.T) {
 // ------------------------------------------------------------------------- //
 
 func Test_SimpleClientTests(t *testing.T) {
+	var unopened string
 	tst.Testing("Simple Client tests", "", simpleClientTests)
 
 	if simpleClientTests {
 		chk.Reset()
-		_, err := NewClient(loopback, 0, false)
+		l, err := NewListener(loopback, nil) // find a free port, then close it
+		chk.Err(err, "Failed to create loopback listener", t.FailNow)
+		unopened = l.Addr()
+		l.Close()
+		_, err = NewClient(unopened, 0, false)
 		chk.ErrIs(err, nwk.Err_ConnectionRefused)
 		chk.ShowPassFail(t, "Refused by unopened listener")
 	}
 
 	if simpleClientTests {
 		chk.Reset()
-		_, err := NewClient(loopback, time.Microsecond, false)
+		_, err := NewClient(unopened, time.Microsecond, false)
 		chk.ErrIs(err, nwk.Err_Timeout)
 		chk.ShowPassFail(t, "Timeout on listener")
 	}
@@ -214,6 +247,8 @@ func Test_SimpleReadTests(t *testing.T) {
 			if nil != err {
 				serrPipe <- err
 			} else {
+				_, ok := UnwrapConn(s).(*net.TCPConn)
+				chk.Tru(ok, "UnwrapConn not a TCPConn")
 				srw := NewReadWriter(s)
 				err = srw.WriteByte(0xAB)
 				chk.Err(err, "Write byte failed")
@@ -222,7 +257,7 @@ func Test_SimpleReadTests(t *testing.T) {
 		}()
 
 		chk.Err(waitFor("Listener Waiting"))
-		crw, err := NewClient(loopback, 0, false)
+		crw, err := NewClient(l.Addr(), 0, false)
 		chk.Err(err, "Failed to create client", t.FailNow)
 		r, err := crw.ReadByte()
 		chk.Tru(r == 0xAB, "ReadByte invalid")
@@ -257,7 +292,7 @@ func Test_SimpleReadTests(t *testing.T) {
 
 		b := make([]byte, 15)
 		chk.Err(waitFor("Listener Waiting"))
-		crw, err := NewClient(loopback, 0, false)
+		crw, err := NewClient(l.Addr(), 0, false)
 		chk.Err(err, "Failed to create client", t.FailNow)
 		r, err := crw.Read(b)
 		chk.Tru(r == 15, "Read invalid")
@@ -292,7 +327,7 @@ func Test_SimpleReadTests(t *testing.T) {
 		}()
 
 		chk.Err(waitFor("Listener Waiting"))
-		crw, err := NewClient(loopback, 0, false)
+		crw, err := NewClient(l.Addr(), 0, false)
 		chk.Err(err, "Failed to create client", t.FailNow)
 		r, err := crw.ReadString() // read the 'hello' message
 		chk.Tru(r == "Hello, you have reached the test listener\n", "ReadString invalid")
@@ -330,7 +365,7 @@ func Test_SimpleReadTimeout(t *testing.T) {
 		}()
 
 		chk.Err(waitFor("Listener Waiting"))
-		crw, err := NewClient(loopback, 0, false)
+		crw, err := NewClient(l.Addr(), 0, false)
 		chk.Err(err, "Failed to create client", t.FailNow)
 		crw.ReadTimeout(time.Second)
 		_, err = crw.ReadString() // try to read the 'hello' message
@@ -377,7 +412,7 @@ func Test_ReadConnTests(t *testing.T) {
 		chk.Reset()
 		for i := 0; i < 10 && chk.Ok(); i++ {
 			chk.Err(waitFor("Listener Waiting"))
-			crw, err := NewClient(loopback, 0, false)
+			crw, err := NewClient(l.Addr(), 0, false)
 			chk.Err(err, "Failed to create client", t.FailNow)
 			r, err := crw.ReadString() // read the 'hello' message
 			chk.Tru(r == "Hello, you have reached the test listener\n", "ReadString invalid")
@@ -423,7 +458,7 @@ func Test_SimpleWriteTests(t *testing.T) {
 		}()
 
 		chk.Err(waitFor("Listener Waiting"))
-		crw, err := NewClient(loopback, 0, false)
+		crw, err := NewClient(l.Addr(), 0, false)
 		chk.Err(err, "Failed to create client", t.FailNow)
 		err = crw.WriteByte(0xBA)
 		chk.Err(err, "Write byte failed")
@@ -459,7 +494,7 @@ func Test_SimpleWriteTests(t *testing.T) {
 		}()
 
 		chk.Err(waitFor("Listener Waiting"))
-		crw, err := NewClient(loopback, 0, false)
+		crw, err := NewClient(l.Addr(), 0, false)
 		chk.Err(err, "Failed to create client", t.FailNow)
 		err = crw.Write([]byte("String as bytes"))
 		chk.Err(err, "Write []byte failed")
@@ -493,7 +528,7 @@ func Test_SimpleWriteTests(t *testing.T) {
 		}()
 
 		chk.Err(waitFor("Listener Waiting"))
-		crw, err := NewClient(loopback, 0, false)
+		crw, err := NewClient(l.Addr(), 0, false)
 		chk.Err(err, "Failed to create client", t.FailNow)
 		err = crw.WriteString("Hello, I am the simple test client\n")
 		chk.Err(err, "Write string failed")
@@ -527,7 +562,7 @@ func Test_SimpleWriteTimeout(t *testing.T) {
 		}()
 
 		chk.Err(waitFor("Listener Waiting"))
-		crw, err := NewClient(loopback, 0, true)
+		crw, err := NewClient(l.Addr(), 0, true)
 		chk.Err(err, "Failed to create client", t.FailNow)
 		crw.WriteTimeout(time.Microsecond)
 		err = crw.WriteString("This will fail on close")
@@ -582,7 +617,7 @@ func Test_WriteConnTests(t *testing.T) {
 		chk.Reset()
 		for i := 0; i < 10 && chk.Ok(); i++ {
 			chk.Err(waitFor("Listener Waiting"))
-			crw, err := NewClient(loopback, 0, false)
+			crw, err := NewClient(l.Addr(), 0, false)
 			chk.Err(err, "Failed to create client", t.FailNow)
 			err = crw.WriteString("Hello, I am the simple test client\n")
 			chk.Err(err, "Write failed")
@@ -690,10 +725,10 @@ forloop:
 	return err
 }
 
-func doRecordRequests(num int) {
+func doRecordRequests(addr string, num int) {
 	for num > 0 {
 		num -= 1
-		crw, err := NewClient(loopback, time.Millisecond*100, false)
+		crw, err := NewClient(addr, time.Millisecond*100, false)
 		chk.Err(err, "Failed to create client")
 		if nil != err {
 			cerrPipe <- err
@@ -731,11 +766,11 @@ func doRecordRequests(num int) {
 	tstatPipe <- "Exiting test"
 }
 
-func doSizedRecords(num int) {
+func doSizedRecords(addr string, num int) {
 	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
 	for num > 0 {
 		num -= 1
-		crw, err := NewClient(loopback, time.Millisecond*100, false)
+		crw, err := NewClient(addr, time.Millisecond*100, false)
 		chk.Err(err, "Failed to create client")
 		if nil != err {
 			cerrPipe <- err
@@ -774,14 +809,14 @@ func doSizedRecords(num int) {
 	tstatPipe <- "Exiting test"
 }
 
-func doStructRecords(num int) {
+func doStructRecords(addr string, num int) {
 	var is inputStruct
 	var os outputStruct
 	var bo binary.ByteOrder
 	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
 
 	// let's send multiple structs in this exchange
-	crw, err := NewClient(loopback, time.Millisecond*100, false)
+	crw, err := NewClient(addr, time.Millisecond*100, false)
 	chk.Err(err, "Failed to create client")
 	if nil != err {
 		cerrPipe <- err
@@ -934,7 +969,7 @@ func Test_Records(t *testing.T) {
 		l, err := NewListener(loopback, sstatPipe) // use server stat pipe
 		chk.Err(err, "Failed to create loopback listener", t.FailNow)
 
-		go doRecordRequests(8)
+		go doRecordRequests(l.Addr(), 8)
 		go l.HandleRequests(recordConnHandler, serrPipe)
 
 		chk.Err(waitFor("Exiting test"))
@@ -948,7 +983,7 @@ func Test_Records(t *testing.T) {
 		l, err := NewListener(loopback, sstatPipe) // use server stat pipe
 		chk.Err(err, "Failed to create loopback listener", t.FailNow)
 
-		go doSizedRecords(8)
+		go doSizedRecords(l.Addr(), 8)
 		go l.HandleRequests(recordConnHandler, serrPipe)
 
 		chk.Err(waitFor("Exiting test"))
@@ -962,7 +997,7 @@ func Test_Records(t *testing.T) {
 		l, err := NewListener(loopback, sstatPipe) // use server stat pipe
 		chk.Err(err, "Failed to create loopback listener", t.FailNow)
 
-		go doStructRecords(8)
+		go doStructRecords(l.Addr(), 8)
 		go l.HandleRequests(recordConnHandler, serrPipe)
 
 		chk.Err(waitFor("Exiting test"))
@@ -974,6 +1009,1354 @@ func Test_Records(t *testing.T) {
 
 // ------------------------------------------------------------------------- //
 
+func Test_ListenerEvents(t *testing.T) {
+	tst.Testing("Typed listener events", "", listenerEvents)
+
+	if listenerEvents {
+		chk.Reset()
+		evts := make(chan ListenerEvent, 8)
+		l, err := NewEventListener(loopback, evts)
+		chk.Err(err, "Failed to create loopback listener", t.FailNow)
+		ev := <-evts
+		chk.Tru(EvCreated == ev.Kind && "Listener Created" == ev.String(), "Expected Created event")
+		go func() {
+			l.HandleARequest(func(cn int, serving string, rw ReadWriter) error {
+				r, err := rw.ReadString()
+				if nil == err {
+					err = rw.WriteString(strings.ToUpper(r))
+				}
+				return err
+			})
+		}()
+		ev = <-evts
+		chk.Tru(EvWaiting == ev.Kind, "Expected Waiting event")
+		crw, err := NewClient(l.Addr(), 0, false)
+		chk.Err(err, "Failed to create client", t.FailNow)
+		chk.Err(crw.WriteString("hello\n"))
+		r, err := crw.ReadString()
+		chk.Err(err, "ReadString failed: %v", err)
+		chk.Tru("HELLO\n" == r, "ReadString invalid")
+		crw.Close()
+
+		ev = <-evts
+		chk.Tru(EvConnected == ev.Kind && 1 == ev.ConnNum, "Expected Connected event")
+		chk.Tru(fmt.Sprintf("Con1@%s", ev.Remote) == ev.String(), "Connected string invalid")
+		ev = <-evts
+		chk.Tru(EvDisconnected == ev.Kind && 1 == ev.ConnNum, "Expected Disconnected event")
+		chk.Tru(6 == ev.BytesIn && 6 == ev.BytesOut, "Byte counts invalid")
+		chk.Tru(0 < ev.Duration, "Duration not set")
+		chk.Tru(fmt.Sprintf("Dis1@%s(<nil>)", ev.Remote) == ev.String(), "Disconnected string invalid")
+		l.Close()
+		ev = <-evts
+		chk.Tru(EvClosed == ev.Kind && "Listener Closed" == ev.String(), "Expected Closed event")
+		chk.ShowPassFail(t, "Listener events")
+	}
+}
+
+// ------------------------------------------------------------------------- //
+
+func Test_ListenerStats(t *testing.T) {
+	tst.Testing("Listener connection statistics", "", listenerStats)
+
+	if listenerStats {
+		chk.Reset()
+		l, err := NewListener(loopback, tstatPipe)
+		chk.Err(err, "Failed to create loopback listener", t.FailNow)
+		chk.Err(waitFor("Listener Created"), t.FailNow)
+		go l.HandleRequests(func(cn int, serving string, rw ReadWriter) error {
+			r, err := rw.ReadString()
+			if nil != err {
+				return err
+			}
+			if "quit\n" == r {
+				return nwk.Err_ClosedByUser
+			}
+			return rw.WriteString(r)
+		}, nil)
+
+		for _, req := range []string{"echo\n", "quit\n", "echo\n"} {
+			chk.Err(waitFor("Listener Waiting"))
+			crw, err := NewClient(l.Addr(), 0, false)
+			chk.Err(err, "Failed to create client", t.FailNow)
+			chk.Err(crw.WriteString(req))
+			crw.ReadString() // echo or EOF
+			crw.Close()
+		}
+		chk.Err(waitFor("Listener Waiting"))
+		time.Sleep(time.Millisecond * 100)
+		st := l.Stats()
+		chk.Tru(3 == st.Connections && 0 == st.Servicing, "Counts invalid")
+		chk.Tru(1 <= st.PeakServicing, "Peak invalid")
+		chk.Tru(15 == st.BytesIn && 10 == st.BytesOut, "Byte totals invalid")
+		chk.Tru(1 == st.HandlerErrors[nwk.Err_ClosedByUser], "Handler errors invalid")
+		chk.Tru(0 == len(st.Active), "Active connections invalid")
+
+		// an idle client shows as active until it closes
+		crw, err := NewClient(l.Addr(), 0, false)
+		chk.Err(err, "Failed to create client", t.FailNow)
+		time.Sleep(time.Millisecond * 100)
+		st = l.Stats()
+		chk.Tru(1 == st.Servicing && 1 == len(st.Active) && 4 == st.Active[0].ConnNum, "Active connection missing")
+		crw.Close()
+		chk.Err(waitFor("Listener Waiting"))
+		time.Sleep(time.Millisecond * 100)
+		st = l.Stats()
+		chk.Tru(0 == st.Servicing && 0 == len(st.Active), "Active connection not removed")
+		chk.Tru(1 == st.HandlerErrors[io.EOF], "Handler EOF not counted")
+
+		l.Close()
+		chk.Err(waitFor("Listener Closed"))
+		chk.ShowPassFail(t, "Listener stats")
+	}
+}
+
+// ------------------------------------------------------------------------- //
+
+func Test_IdleHandler(t *testing.T) {
+	tst.Testing("Idle handler on accept timeout", "", idleHandler)
+
+	if idleHandler {
+		chk.Reset()
+		l, err := NewListener(loopback, nil)
+		chk.Err(err, "Failed to create loopback listener", t.FailNow)
+		idles := 0
+		l.SetTimeout(time.Millisecond * 200)
+		l.SetIdleHandler(func(*Listener) bool {
+			idles++
+			return 3 == idles
+		})
+		done := make(chan error)
+		go func() {
+			done <- l.HandleRequests(func(cn int, serving string, rw ReadWriter) error {
+				return rw.WriteString("Hello\n")
+			}, nil)
+		}()
+
+		time.Sleep(time.Millisecond * 300) // served after the 1st idle call
+		crw, err := NewClient(l.Addr(), 0, false)
+		chk.Err(err, "Failed to create client", t.FailNow)
+		r, err := crw.ReadString()
+		chk.Tru("Hello\n" == r, "ReadString invalid")
+		crw.Close()
+
+		chk.ErrIs(<-done, nwk.Err_Timeout)
+		chk.Tru(3 == idles, "IdleHandler not called 3 times")
+		l.Close()
+		chk.ShowPassFail(t, "Serve through idle timeouts")
+	}
+}
+
+// ------------------------------------------------------------------------- //
+
+// reads lines until an error
+func readLinesHandler(connectionNumber int, serving string, rw ReadWriter) error {
+	for {
+		if _, err := rw.ReadString(); nil != err {
+			return err
+		}
+	}
+}
+
+// make a connection writing a line every 'every' until 'total' passes,
+//	returns the Disconnected event
+func connDeadlineTest(l *Listener, evts chan ListenerEvent, every, total time.Duration) ListenerEvent {
+	go l.HandleARequest(readLinesHandler)
+	crw, err := NewClient(l.Addr(), 0, false)
+	chk.Err(err, "Failed to create client")
+	if nil == err {
+		for end := time.Now().Add(total); time.Now().Before(end); time.Sleep(every) {
+			crw.WriteString("ping\n")
+		}
+		crw.Close()
+	}
+	for ev := range evts {
+		if EvDisconnected == ev.Kind {
+			return ev
+		}
+	}
+	return ListenerEvent{}
+}
+
+func Test_ConnDeadlines(t *testing.T) {
+	tst.Testing("Listener connection deadlines", "", connDeadlines)
+
+	if connDeadlines {
+		chk.Reset()
+		evts := make(chan ListenerEvent, 16)
+		l, err := NewEventListener(loopback, evts)
+		chk.Err(err, "Failed to create loopback listener", t.FailNow)
+
+		l.SetIdleTimeout(time.Millisecond * 300)
+		ev := connDeadlineTest(l, evts, time.Millisecond*100, time.Millisecond*600)
+		chk.Tru(nil == ev.Err || io.EOF == ev.Err, "Active connection closed: %v", ev.Err)
+		ev = connDeadlineTest(l, evts, time.Millisecond*500, time.Millisecond*900)
+		chk.ErrIs(ev.Err, nwk.Err_IdleTimeout)
+		chk.Tru(fmt.Sprintf("Dis%d@%s(Idle timeout)", ev.ConnNum, ev.Remote) == ev.String(), "Disconnect reason invalid")
+		l.SetIdleTimeout(0)
+
+		l.SetMaxLifetime(time.Millisecond * 400)
+		ev = connDeadlineTest(l, evts, time.Millisecond*100, time.Millisecond*800)
+		chk.ErrIs(ev.Err, nwk.Err_MaxLifetime)
+		l.SetMaxLifetime(0)
+
+		l.SetConnTimeouts(time.Millisecond*200, 0)
+		ev = connDeadlineTest(l, evts, time.Millisecond*400, time.Millisecond*500)
+		chk.ErrIs(ev.Err, nwk.Err_Timeout)
+
+		l.Close()
+		chk.ShowPassFail(t, "Idle, lifetime and read timeouts")
+	}
+}
+
+// ------------------------------------------------------------------------- //
+
+func Test_BufferedHandler(t *testing.T) {
+	tst.Testing("Buffered writes for ConnHandlers", "", bufferedHandler)
+
+	if bufferedHandler {
+		chk.Reset()
+		l, err := NewListener(loopback, tstatPipe)
+		chk.Err(err, "Failed to create loopback listener", t.FailNow)
+		chk.Err(waitFor("Listener Created"), t.FailNow)
+		l.SetBufferedWrites(true, true)
+		go l.HandleARequest(func(cn int, serving string, rw ReadWriter) error {
+			for _, s := range []string{"Hello", ", who ", "are you?\n"} {
+				if err := rw.WriteString(s); nil != err {
+					return err
+				}
+			}
+			r, err := rw.ReadString() // flushes the greeting
+			if nil != err {
+				return err
+			}
+			return rw.WriteString("Bye " + r) // flushed on return
+		})
+
+		chk.Err(waitFor("Listener Waiting"))
+		crw, err := NewClient(l.Addr(), 0, false)
+		chk.Err(err, "Failed to create client", t.FailNow)
+		crw.ReadTimeout(time.Second)
+		r, err := crw.ReadString()
+		chk.Err(err, "ReadString failed: %v", err)
+		chk.Tru("Hello, who are you?\n" == r, "Greeting invalid")
+		chk.Err(crw.WriteString("tester\n"))
+		r, err = crw.ReadString()
+		chk.Err(err, "ReadString failed: %v", err)
+		chk.Tru("Bye tester\n" == r, "Reply invalid")
+		crw.Close()
+		chk.Err(waitFor(fmt.Sprintf("Dis1@%s(<nil>)", crw.(*readWriter).conn.LocalAddr())))
+
+		l.Close()
+		chk.Err(waitFor("Listener Closed"))
+		chk.ShowPassFail(t, "Buffered handler with flush on read")
+
+		chk.Reset()
+		l, err = NewListener(loopback, nil)
+		chk.Err(err, "Failed to create loopback listener", t.FailNow)
+		l.SetBufferedWrites(true, false)
+		l.SetConnTimeouts(0, time.Millisecond*100)
+		done := make(chan error, 1)
+		go func() {
+			done <- l.HandleARequest(func(cn int, serving string, rw ReadWriter) error {
+				err := rw.WriteString("Slow reply\n")
+				time.Sleep(time.Millisecond * 250) // past the write timeout
+				return err
+			})
+		}()
+		crw, err = NewClient(l.Addr(), 0, false)
+		chk.Err(err, "Failed to create client", t.FailNow)
+		crw.ReadTimeout(time.Second)
+		r, err = crw.ReadString()
+		chk.Tru(nil == err && "Slow reply\n" == r, "Reply flushed after a slow handler invalid")
+		chk.Err(<-done, "Handler flush failed")
+		crw.Close()
+		l.Close()
+		chk.ShowPassFail(t, "Flush after the write timeout")
+	}
+}
+
+// ------------------------------------------------------------------------- //
+
+func Test_HandlerPanics(t *testing.T) {
+	tst.Testing("Recover panics in ConnHandlers", "", handlerPanics)
+
+	if handlerPanics {
+		chk.Reset()
+		evts := make(chan ListenerEvent, 16)
+		l, err := NewEventListener(loopback, evts)
+		chk.Err(err, "Failed to create loopback listener", t.FailNow)
+		go l.HandleRequests(func(cn int, serving string, rw ReadWriter) error {
+			if 1 == cn {
+				var m map[string]int
+				m["boom"]++ // panics, nil map
+			}
+			return rw.WriteString("Still serving\n")
+		}, nil)
+
+		for i := 0; i < 2; i++ {
+			crw, err := NewClient(l.Addr(), 0, false)
+			chk.Err(err, "Failed to create client", t.FailNow)
+			r, err := crw.ReadString()
+			if 0 == i {
+				chk.ErrIs(err, io.EOF)
+			} else {
+				chk.Tru("Still serving\n" == r, "Second connection not served")
+			}
+			crw.Close()
+		}
+
+		var pe *nwk.PanicError
+		for ev := range evts {
+			if EvDisconnected == ev.Kind {
+				chk.Tru(errors.Is(ev.Err, nwk.Err_HandlerPanic), "Expected Err_HandlerPanic")
+				chk.Tru(errors.As(ev.Err, &pe), "Expected *PanicError")
+				break
+			}
+		}
+		chk.Tru(nil != pe && bytes.Contains(pe.Stack, []byte("Test_HandlerPanics")), "Stack missing")
+		time.Sleep(time.Millisecond * 100)
+		st := l.Stats()
+		chk.Tru(0 == st.Servicing && 1 == st.HandlerErrors[nwk.Err_HandlerPanic], "Stats invalid")
+		l.Close()
+		chk.ShowPassFail(t, "Handler panic recovered")
+
+		chk.Reset()
+		l, err = NewListener(loopback, nil)
+		chk.Err(err, "Failed to create loopback listener", t.FailNow)
+		l.SetRePanic(true)
+		raised := make(chan interface{}, 1)
+		go func() {
+			defer func() { raised <- recover() }()
+			l.HandleARequest(func(cn int, serving string, rw ReadWriter) error {
+				var m map[string]int
+				m["boom"]++ // panics, nil map
+				return nil
+			})
+		}()
+		crw, err := NewClient(l.Addr(), 0, false)
+		chk.Err(err, "Failed to create client", t.FailNow)
+		_, err = crw.ReadString()
+		chk.ErrIs(err, io.EOF)
+		crw.Close()
+		pe, _ = (<-raised).(*nwk.PanicError)
+		chk.Tru(nil != pe && 0 != len(pe.Stack), "Expected a *PanicError re-panic")
+		if nil != pe {
+			_, ok := pe.Value.(runtime.Error)
+			chk.Tru(ok, "Panic value not the runtime.Error")
+		}
+		l.Close()
+		chk.ShowPassFail(t, "Handler panic raised again")
+	}
+}
+
+// ------------------------------------------------------------------------- //
+
+// connect and read the greeting, returns the error from reading
+func admissionTest(addr string, crws *[]ReadWriter) error {
+	crw, err := NewClient(addr, 0, false)
+	if nil != err {
+		return err
+	}
+	*crws = append(*crws, crw)
+	crw.ReadTimeout(time.Second)
+	_, err = crw.ReadString()
+	return err
+}
+
+func Test_AdmissionControl(t *testing.T) {
+	tst.Testing("Listener admission control", "", admissionControl)
+
+	greet := func(cn int, serving string, rw ReadWriter) error {
+		if err := rw.WriteString("Hello\n"); nil != err {
+			return err
+		}
+		_, err := rw.ReadString() // wait for client to close
+		return err
+	}
+
+	if admissionControl {
+		chk.Reset()
+		l, err := NewListener(loopback, tstatPipe)
+		chk.Err(err, "Failed to create loopback listener", t.FailNow)
+		chk.Err(waitFor("Listener Created"), t.FailNow)
+		chk.ErrIs(l.Deny("not.an.ip"), nwk.Err_IllegalParam)
+		chk.Err(l.Allow("10.0.0.0/8", "::1"))
+		go l.HandleRequests(greet, nil)
+		chk.Err(waitFor("Listener Waiting"))
+
+		crws := []ReadWriter{}
+		chk.ErrIs(admissionTest(l.Addr(), &crws), io.EOF)
+		chk.Err(waitFor(fmt.Sprintf("Rej@%s(Address denied)", crws[0].(*readWriter).conn.LocalAddr())))
+		chk.Err(l.Allow("127.0.0.1"))
+		chk.Err(admissionTest(l.Addr(), &crws))
+		chk.Err(l.Deny("127.0.0.0/8"))
+		chk.ErrIs(admissionTest(l.Addr(), &crws), io.EOF)
+		for _, c := range crws {
+			c.Close()
+		}
+		time.Sleep(time.Millisecond * 100)
+		st := l.Stats()
+		chk.Tru(2 == st.Rejected[nwk.Err_Denied] && 1 == st.Connections, "Stats invalid")
+		l.Close()
+		chk.Err(waitFor("Listener Closed"))
+		chk.ShowPassFail(t, "Allow & deny lists")
+	}
+
+	if admissionControl {
+		chk.Reset()
+		l, err := NewListener(loopback, nil)
+		chk.Err(err, "Failed to create loopback listener", t.FailNow)
+		l.SetMaxPerIP(2)
+		go l.HandleRequests(greet, nil)
+
+		crws := []ReadWriter{}
+		chk.Err(admissionTest(l.Addr(), &crws))
+		chk.Err(admissionTest(l.Addr(), &crws))
+		chk.ErrIs(admissionTest(l.Addr(), &crws), io.EOF)
+		crws[0].Close()
+		time.Sleep(time.Millisecond * 100)
+		chk.Err(admissionTest(l.Addr(), &crws))
+		for _, c := range crws {
+			c.Close()
+		}
+		chk.Tru(1 == l.Stats().Rejected[nwk.Err_TooManyConns], "Stats invalid")
+		l.Close()
+		chk.ShowPassFail(t, "Max connections per IP")
+	}
+
+	if admissionControl {
+		chk.Reset()
+		l, err := NewListener(loopback, nil)
+		chk.Err(err, "Failed to create loopback listener", t.FailNow)
+		l.SetRateLimit(5, 2)
+		go l.HandleRequests(greet, nil)
+
+		crws := []ReadWriter{}
+		chk.Err(admissionTest(l.Addr(), &crws))
+		chk.Err(admissionTest(l.Addr(), &crws))
+		chk.ErrIs(admissionTest(l.Addr(), &crws), io.EOF)
+		time.Sleep(time.Millisecond * 250) // refills a token
+		chk.Err(admissionTest(l.Addr(), &crws))
+		for _, c := range crws {
+			c.Close()
+		}
+		chk.Tru(1 == l.Stats().Rejected[nwk.Err_RateLimited], "Stats invalid")
+		l.Close()
+		chk.ShowPassFail(t, "Connection rate limit")
+
+		chk.Reset()
+		a := newAdmission()
+		a.rate, a.burst = 0.001, 1 // buckets won't refill during the flood
+		for i := 0; i < 3*maxBuckets; i++ {
+			a.take(fmt.Sprintf("10.%d.%d.%d", i>>16, (i>>8)&0xFF, i&0xFF))
+		}
+		chk.Tru(maxBuckets >= len(a.buckets), "Buckets grew past the limit: %d", len(a.buckets))
+		chk.Tru(!a.take("10.0.11.255"), "Recent bucket evicted") // the last IP taken
+		chk.ShowPassFail(t, "Rate limit buckets bounded")
+	}
+}
+
+// ------------------------------------------------------------------------- //
+
+func Test_MultiListener(t *testing.T) {
+	tst.Testing("Listener on multiple addresses", "", multiListener)
+
+	if multiListener {
+		chk.Reset()
+		m, err := NewMultiListener([]string{loopback, loopback}, nil)
+		chk.Err(err, "Failed to create multi listener", t.FailNow)
+		addrs := m.Addrs()
+		chk.Tru(2 == len(addrs) && addrs[0] != addrs[1], "Addrs invalid")
+		_, err = NewMultiListener([]string{loopback, addrs[1]}, nil)
+		chk.ErrIs(nwk.ChkNetErr(err), nwk.Err_AddressInUse)
+		for _, l := range m.Listeners() {
+			l.SetConnTimeouts(time.Second, time.Second)
+		}
+		done := make(chan error)
+		go func() {
+			done <- m.HandleRequests(func(cn int, serving string, rw ReadWriter) error {
+				return rw.WriteString(fmt.Sprintf("%d\n", cn))
+			}, nil)
+		}()
+
+		for i, a := range []string{addrs[0], addrs[1], addrs[0]} {
+			crw, err := NewClient(a, 0, false)
+			chk.Err(err, "Failed to create client", t.FailNow)
+			r, err := crw.ReadString()
+			chk.Tru(fmt.Sprintf("%d\n", i+1) == r, "Shared connection number invalid")
+			crw.Close()
+		}
+		time.Sleep(time.Millisecond * 100)
+		s, c := m.Counts()
+		chk.Tru(0 == s && 3 == c, "Counts invalid")
+		chk.Tru(3 == m.Stats().Connections, "Stats invalid")
+		m.Close()
+		chk.ErrIs(<-done, nwk.Err_NoConnection)
+		chk.ShowPassFail(t, "Shared counts over 2 addresses")
+	}
+}
+
+func Test_SocketOptions(t *testing.T) {
+	tst.Testing("Listener socket options", "", socketOptions)
+
+	if socketOptions && "linux" == runtime.GOOS {
+		chk.Reset()
+		called := false
+		cfg := ListenConfig{ReuseAddr: true, ReusePort: true, Backlog: 16,
+			Control: func(network, address string, c syscall.RawConn) error {
+				called = true
+				return nil
+			}}
+		l1, err := NewListenerConfig(loopback, nil, cfg)
+		chk.Err(err, "Failed to create listener", t.FailNow)
+		chk.Tru(called, "Control not called")
+		l2, err := NewListenerConfig(l1.Addr(), nil, cfg)
+		chk.Err(err, "Failed to share port with SO_REUSEPORT", t.FailNow)
+		_, err = NewListener(l1.Addr(), nil)
+		chk.ErrIs(err, nwk.Err_AddressInUse)
+		l2.Close()
+		l1.Close()
+		chk.ShowPassFail(t, "SO_REUSEPORT")
+
+		chk.Reset()
+		cerr := errors.New("control failed")
+		_, err = NewListenerConfig(loopback, nil, ListenConfig{Control: func(string, string, syscall.RawConn) error { return cerr }})
+		chk.Tru(errors.Is(err, cerr), "Control error not returned")
+		_, err = NewListenerConfig(loopback, nil, ListenConfig{Control: func(string, string, syscall.RawConn) error { return nwk.Err_NotSupported }})
+		chk.ErrIs(err, nwk.Err_NotSupported)     // as returned when an option isn't supported
+		_, err = NewListener("192.0.2.1:0", nil) // TEST-NET-1, not on this host
+		chk.ErrIs(err, nwk.Err_AddrNotFound)
+		l, err := NewListenerConfig("192.0.2.1:0", nil, ListenConfig{FreeBind: true, FastOpen: 8})
+		chk.Err(err, "Failed to bind with IP_FREEBIND")
+		if nil == err {
+			l.Close()
+		}
+		chk.ShowPassFail(t, "Control, IP_FREEBIND & TCP_FASTOPEN")
+	}
+}
+
+func Test_ListenerHandoff(t *testing.T) {
+	tst.Testing("Passing the listening socket", "", listenerHandoff)
+
+	if listenerHandoff {
+		chk.Reset()
+		l1, err := NewListener(loopback, nil)
+		chk.Err(err, "Failed to create listener", t.FailNow)
+		f, err := l1.File()
+		chk.Err(err, "Failed to get listener file", t.FailNow)
+		fd, err := dupFD(f.Fd()) // NewListenerFromEnv closes the fd it is given
+		f.Close()
+		chk.Err(err, "Failed to dup listener fd", t.FailNow)
+		os.Setenv("NWK_TEST_FD", strconv.Itoa(int(fd)))
+		defer os.Unsetenv("NWK_TEST_FD")
+		l2, err := NewListenerFromEnv("NWK_TEST_FD", nil)
+		chk.Err(err, "Failed to create listener from fd", t.FailNow)
+		chk.Tru(l1.Addr() == l2.Addr(), "Handed off address invalid")
+		l1.Close() // old server stops, the socket stays open
+
+		l2.SetConnTimeouts(time.Second, time.Second)
+		done := make(chan error)
+		go func() {
+			done <- l2.HandleRequests(func(cn int, serving string, rw ReadWriter) error {
+				return rw.WriteString("handed off\n")
+			}, nil)
+		}()
+		crw, err := NewClient(l1.Addr(), 0, false)
+		chk.Err(err, "Failed to connect after handoff", t.FailNow)
+		r, _ := crw.ReadString()
+		chk.Tru("handed off\n" == r, "Read invalid")
+		crw.Close()
+		l2.Close()
+		chk.ErrIs(<-done, nwk.Err_NoConnection)
+		chk.ShowPassFail(t, "File / NewListenerFromEnv")
+
+		chk.Reset()
+		l, err := NewListener(loopback, nil)
+		chk.Err(err, "Failed to create listener", t.FailNow)
+		cmd := exec.Command("true")
+		cmd.ExtraFiles = []*os.File{os.Stdin}
+		chk.Err(l.PassTo(cmd, "NWK_LISTEN_FD"), "PassTo failed")
+		chk.Tru(2 == len(cmd.ExtraFiles), "ExtraFiles invalid")
+		chk.Tru("NWK_LISTEN_FD=4" == cmd.Env[len(cmd.Env)-1], "Env invalid")
+		cmd.ExtraFiles[1].Close()
+		l.Close()
+		_, err = NewListenerFromEnv("NWK_NO_SUCH_VAR", nil)
+		chk.ErrIs(err, nwk.Err_AddrNotFound)
+		os.Setenv("LISTEN_PID", "1")
+		os.Setenv("LISTEN_FDS", "1")
+		_, err = SystemdListeners(nil)
+		chk.ErrIs(err, nwk.Err_AddrNotFound) // not for this process
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		chk.ShowPassFail(t, "PassTo / SystemdListeners")
+	}
+}
+
+func Test_UnixSockets(t *testing.T) {
+	tst.Testing("Unix stream sockets", "", unixSockets)
+
+	if unixSockets {
+		path := filepath.Join(t.TempDir(), "nwk.sock")
+		for _, addr := range []string{path, fmt.Sprintf("@nwk-test-%d", os.Getpid())} {
+			chk.Reset()
+			l, err := NewUnixListener(addr, nil)
+			chk.Err(err, "Failed to create unix listener", t.FailNow)
+			chk.Tru(addr == l.Addr(), "Addr invalid")
+			l.SetConnTimeouts(time.Second, time.Second)
+			done := make(chan error)
+			go func() {
+				done <- l.HandleRequests(func(cn int, serving string, rw ReadWriter) error {
+					cred, err := PeerCred(rw)
+					if nil != err {
+						return err
+					}
+					s, err := rw.ReadString()
+					if nil != err {
+						return err
+					}
+					return rw.WriteString(fmt.Sprintf("%d:%d:%s", cred.Pid, cred.Uid, s))
+				}, nil)
+			}()
+
+			crw, err := NewUnixClient(addr, time.Second, true)
+			chk.Err(err, "Failed to create unix client", t.FailNow)
+			crw.ReadTimeout(time.Second)
+			chk.Err(crw.WriteString("unix\n"), "Write failed")
+			chk.Err(crw.Flush(), "Flush failed")
+			r, err := crw.ReadString()
+			chk.Err(err, "Read failed")
+			if "linux" == runtime.GOOS {
+				chk.Tru(fmt.Sprintf("%d:%d:unix\n", os.Getpid(), os.Getuid()) == r, "PeerCred invalid")
+			}
+			crw.Close()
+			time.Sleep(time.Millisecond * 100)
+			chk.Tru(1 == l.Stats().Connections, "Stats invalid")
+			l.Close()
+			chk.ErrIs(<-done, nwk.Err_NoConnection)
+			chk.ShowPassFail(t, "Unix socket "+addr)
+		}
+		chk.Reset()
+		_, err := os.Stat(path)
+		chk.Tru(os.IsNotExist(err), "Socket file not removed")
+		_, err = PeerCred(NewReadWriter(nil))
+		chk.ErrIs(err, nwk.Err_NotSupported)
+		chk.ShowPassFail(t, "Socket cleanup / PeerCred on non-unix")
+	}
+}
+
+// ReadWriter with all the optional interfaces, as this package's ReadWriters
+type fullRW interface {
+	ReadWriter
+	ConnInfo
+	HalfCloser
+	Peeker
+	Limiter
+	Framer
+	Stuffer
+	Checksummer
+	Liner
+}
+
+var (
+	_ fullRW = (*readWriter)(nil)
+	_ fullRW = (*readBufWriter)(nil)
+)
+
+// Return a connected pair of ReadWriters over an in-memory pipe, writes
+//	to w (buffered if requested) are read from r
+func rwPair(buffered bool) (w, r fullRW) {
+	c1, c2 := net.Pipe()
+	if buffered {
+		return newReadBufWriter(c1), newReadWriter(c2)
+	}
+	return newReadWriter(c1), newReadWriter(c2)
+}
+
+// Run the writes in a go routine as the pipe is unbuffered, returns
+//	the first write error when done
+func pipeWrites(w fullRW, writes ...func(fullRW) error) chan error {
+	done := make(chan error, 1)
+	go func() {
+		var first error
+		for _, fn := range writes {
+			if err := fn(w); nil != err && nil == first {
+				first = err
+			}
+		}
+		if err := w.Flush(); nil == first {
+			first = err
+		}
+		done <- first
+	}()
+	return done
+}
+
+func Test_Frames(t *testing.T) {
+	tst.Testing("Length-prefixed frames", "", frameTests)
+
+	if frameTests {
+		sizes := []int{0, 1, 200, 300, 70000}
+		prefixes := []FramePrefix{FrameU8, FrameU16, FrameU32, FrameVarint}
+		for _, pfx := range prefixes {
+			for _, ord := range []binary.ByteOrder{binary.BigEndian, binary.LittleEndian} {
+				chk.Reset()
+				w, r := rwPair(FrameU16 == pfx)
+				w.SetFraming(pfx, ord, 0)
+				r.SetFraming(pfx, ord, 0)
+				frames := [][]byte{}
+				for _, sz := range sizes {
+					if (FrameU8 == pfx && 255 < sz) || (FrameU16 == pfx && 65535 < sz) {
+						chk.ErrIs(w.WriteFrame(make([]byte, sz)), nwk.Err_TooLarge)
+						continue
+					}
+					f := make([]byte, sz)
+					rand.Read(f)
+					frames = append(frames, f)
+				}
+				done := pipeWrites(w, func(w fullRW) error {
+					for _, f := range frames {
+						if err := w.WriteFrame(f); nil != err {
+							return err
+						}
+					}
+					return nil
+				})
+				for _, f := range frames {
+					d, err := r.ReadFrame()
+					chk.Err(err, "ReadFrame failed")
+					chk.Tru(bytes.Equal(f, d), "Frame data invalid")
+				}
+				chk.Err(<-done, "WriteFrame failed")
+				w.Close()
+				_, err := r.ReadFrame()
+				chk.ErrIs(err, io.EOF)
+				r.Close()
+				chk.ShowPassFail(t, fmt.Sprintf("Frames prefix %d %v", pfx, ord))
+			}
+		}
+
+		chk.Reset()
+		w, r := rwPair(false)
+		r.SetFraming(FrameU16, nil, 100)
+		w.SetFraming(FrameU16, nil, 0)
+		done := pipeWrites(w,
+			func(w fullRW) error { return w.WriteFrame([]byte("twelve bytes")) },
+			func(w fullRW) error { return w.WriteFrame([]byte("fits")) },
+			func(w fullRW) error { return w.WriteFrame(make([]byte, 101)) })
+		buf := make([]byte, 8)
+		_, err := r.ReadFrameInto(buf)
+		chk.ErrIs(err, io.ErrShortBuffer) // discarded, the next frame still reads
+		n, err := r.ReadFrameInto(buf)
+		chk.Err(err, "ReadFrameInto failed")
+		chk.Tru("fits" == string(buf[:n]), "ReadFrameInto data invalid")
+		_, err = r.ReadFrame()
+		chk.ErrIs(err, nwk.Err_TooLarge)
+		r.Close()
+		<-done
+		w.Close()
+
+		w, r = rwPair(false)
+		w.SetFraming(FrameU8, nil, 4)
+		chk.ErrIs(w.WriteFrame([]byte("too long")), nwk.Err_TooLarge)
+		done = pipeWrites(w, func(w fullRW) error { return w.Write([]byte{10, 'p', 'a', 'r', 't'}) })
+		r.SetFraming(FrameU8, nil, 0)
+		go func() { <-done; w.Close() }()
+		_, err = r.ReadFrame()
+		chk.ErrIs(err, io.ErrUnexpectedEOF)
+		r.Close()
+
+		for _, into := range []bool{false, true} { // header only, then closed
+			w, r = rwPair(false)
+			done = pipeWrites(w, func(w fullRW) error { return w.Write([]byte{0, 0, 0, 4}) })
+			go func() { <-done; w.Close() }()
+			if into {
+				_, err = r.ReadFrameInto(make([]byte, 8))
+			} else {
+				_, err = r.ReadFrame()
+			}
+			chk.ErrIs(err, io.ErrUnexpectedEOF)
+			r.Close()
+		}
+
+		w, r = rwPair(false)
+		r.SetFraming(FrameVarint, nil, 0)
+		r.ReadTimeout(time.Millisecond * 50)
+		_, err = r.ReadFrame()
+		chk.ErrIs(err, nwk.Err_Timeout) // a read error, not bad data
+		done = pipeWrites(w, func(w fullRW) error { return w.Write([]byte{0x80}) })
+		_, err = r.ReadFrame()
+		chk.ErrIs(err, nwk.Err_Timeout) // part way through the varint
+		chk.Err(<-done, "Write failed")
+		done = pipeWrites(w, func(w fullRW) error { return w.Write(bytes.Repeat([]byte{0xFF}, 10)) })
+		_, err = r.ReadFrame()
+		chk.ErrIs(err, nwk.Err_BadData) // overflows a uint64
+		chk.Err(<-done, "Write failed")
+		w.Close()
+		r.Close()
+		chk.ShowPassFail(t, "Frame limits, short buffer & unexpected EOF")
+	}
+}
+
+// Write the data a few bytes at a time with a pause between each
+func fragmented(data []byte, every int, pause time.Duration) func(fullRW) error {
+	return func(w fullRW) error {
+		for 0 < len(data) {
+			n := every
+			if n > len(data) {
+				n = len(data)
+			}
+			if err := w.Write(data[:n]); nil != err {
+				return err
+			}
+			data = data[n:]
+			time.Sleep(pause)
+		}
+		return nil
+	}
+}
+
+func Test_FragmentedReads(t *testing.T) {
+	tst.Testing("Reading fragmented records & structs", "", fragmentedReads)
+
+	if fragmentedReads {
+		chk.Reset()
+		in := inputStruct{U32: [4]uint32{1, 2, 3, 4}, Bytes: [8]byte{'f', 'r', 'a', 'g'}}
+		in.F64[2] = 3.25
+		sbuf := new(bytes.Buffer)
+		binary.Write(sbuf, binary.LittleEndian, &in)
+		w, r := rwPair(false)
+		done := pipeWrites(w,
+			fragmented(sbuf.Bytes(), 3, time.Millisecond),
+			fragmented([]byte("junk>>>0123456789"), 2, time.Millisecond))
+		out := inputStruct{}
+		chk.Err(r.ReadStruct(binary.LittleEndian, &out), "ReadStruct of fragmented struct failed")
+		chk.Tru(in == out, "Fragmented struct invalid")
+		b, err := r.ReadSizedRecord([]byte(">>>"), 10)
+		chk.Err(err, "ReadSizedRecord of fragmented record failed")
+		chk.Tru("0123456789" == string(b), "Fragmented record invalid")
+		chk.Err(<-done, "Fragmented writes failed")
+		chk.ErrIs(r.ReadStruct(binary.LittleEndian, &struct{ A []int }{}), nwk.Err_IllegalParam)
+		w.Close()
+		r.Close()
+		chk.ShowPassFail(t, "Fragmented struct & record")
+
+		chk.Reset()
+		w, r = rwPair(false)
+		done = pipeWrites(w, fragmented([]byte(">>>short"), 8, 0))
+		go func() { <-done; w.Close() }()
+		b, err = r.ReadSizedRecord([]byte(">>>"), 10)
+		chk.ErrIs(err, io.ErrUnexpectedEOF)
+		chk.Tru("short" == string(b), "Partial record invalid")
+		r.Close()
+
+		w, r = rwPair(false)
+		done = pipeWrites(w, fragmented([]byte(">>>"), 3, 0)) // start marker only
+		go func() { <-done; w.Close() }()
+		b, err = r.ReadSizedRecord([]byte(">>>"), 10)
+		chk.ErrIs(err, io.ErrUnexpectedEOF)
+		chk.Tru(0 == len(b), "Empty partial record invalid")
+		r.Close()
+
+		w, r = rwPair(false)
+		done = pipeWrites(w, fragmented(sbuf.Bytes()[:20], 20, 0))
+		go func() { <-done; w.Close() }()
+		chk.ErrIs(r.ReadStruct(binary.LittleEndian, &out), io.ErrUnexpectedEOF)
+		r.Close()
+
+		w, r = rwPair(false)
+		w.WriteTimeout(time.Second)
+		r.ReadTimeout(time.Millisecond * 50)
+		done = pipeWrites(w, fragmented(sbuf.Bytes(), 10, time.Millisecond*20))
+		chk.ErrIs(r.ReadStruct(binary.LittleEndian, &out), nwk.Err_Timeout)
+		r.Close()
+		<-done
+		w.Close()
+		chk.ShowPassFail(t, "Short reads & read timeout")
+	}
+}
+
+func Test_StuffedRecords(t *testing.T) {
+	tst.Testing("Byte stuffed records", "", stuffedRecords)
+
+	if stuffedRecords {
+		chk.Reset()
+		seq := func(from, to int) []byte {
+			b := []byte{}
+			for i := from; i <= to; i++ {
+				b = append(b, byte(i))
+			}
+			return b
+		}
+		cobs := []struct{ dta, enc []byte }{ // examples from the COBS paper / wikipedia
+			{[]byte{0}, []byte{1, 1}},
+			{[]byte{0, 0}, []byte{1, 1, 1}},
+			{[]byte{0, 0x11, 0}, []byte{1, 2, 0x11, 1}},
+			{[]byte{0x11, 0x22, 0, 0x33}, []byte{3, 0x11, 0x22, 2, 0x33}},
+			{[]byte{0x11, 0, 0, 0}, []byte{2, 0x11, 1, 1, 1}},
+			{seq(1, 254), append([]byte{0xFF}, seq(1, 254)...)},
+			{seq(0, 254), append([]byte{1, 0xFF}, seq(1, 254)...)},
+			{seq(1, 255), append(append([]byte{0xFF}, seq(1, 254)...), 2, 0xFF)},
+		}
+		for _, c := range cobs {
+			chk.Tru(bytes.Equal(c.enc, cobsEncode(c.dta)), fmt.Sprintf("COBS encode of %x invalid", c.dta))
+			d, err := cobsDecode(c.enc)
+			chk.Err(err, "COBS decode failed")
+			chk.Tru(bytes.Equal(c.dta, d), "COBS decode invalid")
+		}
+		slip, _ := stuff(StuffSLIP, []byte{1, slipEnd, 2, slipEsc})
+		chk.Tru(bytes.Equal([]byte{slipEnd, 1, slipEsc, slipEscEnd, 2, slipEsc, slipEscEsc, slipEnd}, slip), "SLIP encode invalid")
+		hdlc, _ := stuff(StuffHDLC, []byte{hdlcFlag, 1, hdlcEsc})
+		chk.Tru(bytes.Equal([]byte{hdlcFlag, hdlcEsc, 0x5E, 1, hdlcEsc, 0x5D, hdlcFlag}, hdlc), "HDLC encode invalid")
+		chk.ShowPassFail(t, "Encodings")
+
+		for _, mode := range []Stuffing{StuffSLIP, StuffCOBS, StuffHDLC} {
+			chk.Reset()
+			recs := [][]byte{{}, {0}, {slipEnd, slipEsc, hdlcFlag, hdlcEsc, 0}, seq(0, 255), make([]byte, 1000)}
+			rand.Read(recs[4])
+			w, r := rwPair(StuffCOBS == mode)
+			done := pipeWrites(w, func(w fullRW) error {
+				for _, rec := range recs {
+					if err := w.WriteStuffed(mode, rec); nil != err {
+						return err
+					}
+				}
+				return nil
+			})
+			for _, rec := range recs {
+				if 0 == len(rec) && StuffCOBS != mode {
+					continue // an empty SLIP / HDLC record is skipped
+				}
+				d, err := r.ReadStuffed(mode)
+				chk.Err(err, "ReadStuffed failed")
+				chk.Tru(bytes.Equal(rec, d), "Stuffed record invalid")
+			}
+			chk.Err(<-done, "WriteStuffed failed")
+			done = pipeWrites(w, func(w fullRW) error { return w.Write([]byte{0xC0, 0xDB, 0x01, 0xC0, 0x7D, 0x7E, 0xFF, 0x00}) })
+			_, err := r.ReadStuffed(mode)
+			chk.ErrIs(err, nwk.Err_BadData)
+			<-done
+			w.Close()
+			r.Close()
+			chk.ShowPassFail(t, fmt.Sprintf("Stuffed records mode %d", mode))
+		}
+
+		chk.Reset()
+		w, r := rwPair(false)
+		done := pipeWrites(w, func(w fullRW) error { return w.Write([]byte{hdlcFlag, 1, 2}) })
+		go func() { <-done; w.Close() }()
+		_, err := r.ReadStuffed(StuffHDLC)
+		chk.ErrIs(err, io.ErrUnexpectedEOF)
+		_, err = r.ReadStuffed(Stuffing(9))
+		chk.ErrIs(err, nwk.Err_IllegalParam)
+		r.Close()
+		chk.ShowPassFail(t, "Unexpected EOF")
+	}
+}
+
+func Test_MarkerScan(t *testing.T) {
+	tst.Testing("Start / end marker search", "", markerScan)
+
+	if markerScan {
+		chk.Reset()
+		w, r := rwPair(false)
+		done := pipeWrites(w, func(w fullRW) error {
+			return w.WriteString("aaab-rest|xxababcdata abab abcabd|tail")
+		})
+		chk.Err(r.FindStart([]byte("aab")), "FindStart failed")
+		b, err := r.ReadRecord(nil, []byte("|"))
+		chk.Err(err, "ReadRecord failed")
+		chk.Tru("-rest" == string(b), "Overlapping start marker missed")
+		b, err = r.ReadRecord([]byte("ababc"), []byte("abcabd"))
+		chk.Err(err, "ReadRecord failed")
+		chk.Tru("data abab " == string(b), "Overlapping markers invalid")
+		chk.Err(<-done, "Write failed")
+		w.Close()
+		r.Close()
+		chk.ShowPassFail(t, "Self-overlapping markers")
+
+		chk.Reset()
+		w, r = rwPair(false)
+		r.SetScanLimit(10)
+		done = pipeWrites(w, func(w fullRW) error {
+			return w.WriteString("0123456>>>rec<<<0123456789>>>")
+		})
+		b, err = r.ReadRecord([]byte(">>>"), []byte("<<<"))
+		chk.Err(err, "ReadRecord within the scan limit failed")
+		chk.Tru("rec" == string(b), "Record invalid")
+		chk.ErrIs(r.FindStart([]byte(">>>")), nwk.Err_ScanLimit)
+		chk.ErrIs(nwk.ErrClass(nwk.Err_ScanLimit), nwk.Err_ScanLimit)
+		r.Close()
+		<-done
+		w.Close()
+		chk.ShowPassFail(t, "Scan limit")
+	}
+}
+
+func Test_RecordChecksums(t *testing.T) {
+	tst.Testing("Record checksums", "", recordChecksums)
+
+	if recordChecksums {
+		chk.Reset()
+		check := []byte("123456789")
+		chk.Tru(0x29B1 == ChecksumOf(SumCRC16, check), "CRC-16/CCITT check value invalid")
+		chk.Tru(0xCBF43926 == ChecksumOf(SumCRC32, check), "CRC-32 check value invalid")
+		chk.Tru(0x091E01DE == ChecksumOf(SumAdler32, check), "Adler-32 check value invalid")
+		chk.Tru(0x31 == ChecksumOf(SumXOR, check), "XOR check value invalid")
+		chk.Tru(0xDD == ChecksumOf(SumAdd, check), "Sum check value invalid")
+		chk.ShowPassFail(t, "Check values")
+
+		for _, sum := range []Checksum{SumNone, SumCRC16, SumCRC32, SumAdler32, SumXOR, SumAdd} {
+			chk.Reset()
+			w, r := rwPair(SumCRC32 == sum)
+			w.SetChecksum(sum, binary.LittleEndian)
+			r.SetChecksum(sum, binary.LittleEndian)
+			done := pipeWrites(w,
+				func(w fullRW) error { return w.WriteRecord([]byte("<"), []byte(">"), check) },
+				func(w fullRW) error { return w.WriteSizedRecord([]byte("##"), check) })
+			b, err := r.ReadRecord([]byte("<"), []byte(">"))
+			chk.Err(err, "ReadRecord failed")
+			chk.Tru(bytes.Equal(check, b), "Record invalid")
+			b, err = r.ReadSizedRecord([]byte("##"), len(check))
+			chk.Err(err, "ReadSizedRecord failed")
+			chk.Tru(bytes.Equal(check, b), "Sized record invalid")
+			chk.Err(<-done, "Write failed")
+
+			if SumNone != sum {
+				done = pipeWrites(w, func(w fullRW) error {
+					return w.Write([]byte("<12345678X>\x00\x00\x00\x00"))
+				})
+				b, err = r.ReadRecord([]byte("<"), []byte(">"))
+				var ce *ChecksumError
+				chk.Tru(errors.As(err, &ce) && sum == ce.Sum, "ChecksumError not returned")
+				chk.ErrIs(nwk.ErrClass(err), nwk.Err_BadData)
+				chk.Tru("12345678X" == string(b), "Bad record data not returned")
+				<-done
+			}
+			w.Close()
+			r.Close()
+			chk.ShowPassFail(t, fmt.Sprintf("%v trailer", sum))
+		}
+	}
+}
+
+func Test_ReadLimits(t *testing.T) {
+	tst.Testing("Line, record & read budget limits", "", readLimits)
+
+	if readLimits {
+		chk.Reset()
+		w, r := rwPair(false)
+		r.SetMaxLine(8)
+		r.SetMaxRecord(6)
+		done := pipeWrites(w,
+			func(w fullRW) error { return w.WriteString("short\n1234567\nmuch too long\n") },
+			func(w fullRW) error { return w.WriteString("<ok>\n<record too big>\n") },
+			func(w fullRW) error { return w.WriteStuffed(StuffCOBS, []byte("cobs")) },
+			func(w fullRW) error { return w.WriteStuffed(StuffCOBS, []byte("too big")) })
+		l, err := r.ReadString()
+		chk.Tru(nil == err && "short\n" == l, "Short line invalid")
+		b, err := r.ReadBytes()
+		chk.Tru(nil == err && "1234567\n" == string(b), "Line at the limit invalid")
+		l, err = r.ReadString()
+		chk.ErrIs(err, nwk.Err_TooLarge)
+		chk.Tru("much too" == l, "Partial line invalid")
+		r.FindStart([]byte("\n"))
+		b, err = r.ReadRecord([]byte("<"), []byte(">"))
+		chk.Tru(nil == err && "ok" == string(b), "Record invalid")
+		b, err = r.ReadRecord([]byte("<"), []byte(">"))
+		chk.ErrIs(err, nwk.Err_TooLarge)
+		chk.Tru("record" == string(b), "Partial record invalid")
+		r.FindStart([]byte("\n"))
+		b, err = r.ReadStuffed(StuffCOBS)
+		chk.Tru(nil == err && "cobs" == string(b), "Stuffed record invalid")
+		_, err = r.ReadStuffed(StuffCOBS)
+		chk.ErrIs(err, nwk.Err_TooLarge)
+		_, err = r.ReadSizedRecord(nil, 7)
+		chk.ErrIs(err, nwk.Err_TooLarge)
+		chk.Err(<-done, "Write failed")
+		w.Close()
+		r.Close()
+		chk.ShowPassFail(t, "Line & record limits")
+
+		chk.Reset()
+		w, r = rwPair(true)
+		r.SetReadBudget(20)
+		done = pipeWrites(w, func(w fullRW) error {
+			w.WriteTimeout(time.Millisecond * 100)
+			return w.WriteString("0123456789\n0123456789\n")
+		})
+		l, err = r.ReadString()
+		chk.Tru(nil == err && "0123456789\n" == l, "Line within the budget invalid")
+		l, err = r.ReadString()
+		chk.ErrIs(err, nwk.Err_TooLarge)
+		chk.Tru("012345678" == l, "Line past the budget invalid")
+		chk.Tru(20 == r.BytesRead(), "BytesRead invalid")
+		_, err = r.ReadByte()
+		chk.ErrIs(err, nwk.Err_TooLarge)
+		r.Close()
+		<-done
+		w.Close()
+		chk.ShowPassFail(t, "Read budget")
+	}
+}
+
+func Test_LineModes(t *testing.T) {
+	tst.Testing("CRLF, CR & any line endings", "", lineModes)
+
+	if lineModes {
+		chk.Reset()
+		w, r := rwPair(true)
+		w.SetLineMode(LineCRLF, false)
+		r.SetLineMode(LineCRLF, true)
+		done := pipeWrites(w,
+			func(w fullRW) error { return w.WriteLine("one") },
+			func(w fullRW) error { return w.WriteString("a\nb\r\n") })
+		l, err := r.ReadString()
+		chk.Tru(nil == err && "one" == l, "CRLF line invalid")
+		b, err := r.ReadBytes()
+		chk.Tru(nil == err && "a\nb" == string(b), "CRLF line with LF invalid")
+		chk.Err(<-done, "Write failed")
+		w.Close()
+		r.Close()
+		chk.ShowPassFail(t, "CRLF lines")
+
+		chk.Reset()
+		w, r = rwPair(false)
+		r.SetLineMode(LineAny, false)
+		done = pipeWrites(w,
+			func(w fullRW) error { return w.WriteString("x\r\ny\nz\r") },
+			func(w fullRW) error { return w.WriteString("\nlast\r\n") })
+		for _, want := range []string{"x\r\n", "y\n", "z\r", "last\r\n"} {
+			l, err = r.ReadString()
+			chk.Tru(nil == err && want == l, "Any line invalid: %q", l)
+		}
+		chk.Err(<-done, "Write failed")
+		w.Close()
+		r.Close()
+		chk.ShowPassFail(t, "Any line endings")
+
+		chk.Reset()
+		w, r = rwPair(false)
+		w.SetLineMode(LineCR, false)
+		r.SetLineMode(LineCR, true)
+		done = pipeWrites(w,
+			func(w fullRW) error { return w.WriteLine("cr\nline") },
+			func(w fullRW) error { w.SetEOL(';'); return w.WriteLine("semi") })
+		l, err = r.ReadString()
+		chk.Tru(nil == err && "cr\nline" == l, "CR line invalid")
+		r.SetEOL(';')
+		l, err = r.ReadString()
+		chk.Tru(nil == err && "semi" == l, "EOL line invalid")
+		chk.Err(<-done, "Write failed")
+		w.Close()
+		r.Close()
+		chk.ShowPassFail(t, "CR & EOL lines")
+	}
+}
+
+func Test_Streams(t *testing.T) {
+	tst.Testing("io adapters for ReadWriter", "", ioStreams)
+
+	if ioStreams {
+		var (
+			_ io.Reader       = (*Stream)(nil)
+			_ io.Writer       = (*Stream)(nil)
+			_ io.ByteScanner  = (*Stream)(nil)
+			_ io.ByteWriter   = (*Stream)(nil)
+			_ io.StringWriter = (*Stream)(nil)
+			_ io.ReaderFrom   = (*Stream)(nil)
+			_ io.WriterTo     = (*Stream)(nil)
+			_ io.Closer       = (*Stream)(nil)
+		)
+
+		chk.Reset()
+		data := strings.Repeat("0123456789abcdef", 5000)
+		w, r := rwPair(true)
+		done := pipeWrites(w, func(w fullRW) error {
+			n, err := NewStream(w).ReadFrom(strings.NewReader(data))
+			if nil == err && int64(len(data)) != n {
+				err = io.ErrShortWrite
+			}
+			w.Flush()
+			w.Close()
+			return err
+		})
+		got := bytes.Buffer{}
+		n, err := io.Copy(&got, NewStream(r))
+		chk.Err(err, "io.Copy from Stream failed: %v", err)
+		chk.Tru(int64(len(data)) == n && data == got.String(), "Copied data invalid")
+		chk.Err(<-done, "ReadFrom failed")
+		r.Close()
+		chk.ShowPassFail(t, "io.Copy")
+
+		chk.Reset()
+		type msg struct {
+			Name  string
+			Count int
+		}
+		w, r = rwPair(false)
+		done = pipeWrites(w, func(w fullRW) error {
+			return json.NewEncoder(NewStream(w)).Encode(msg{"stream", 3})
+		})
+		m := msg{}
+		err = json.NewDecoder(NewStream(r)).Decode(&m)
+		chk.Tru(nil == err && "stream" == m.Name && 3 == m.Count, "JSON decode invalid")
+		chk.Err(<-done, "JSON encode failed")
+		r.ReadTimeout(time.Millisecond * 50)
+		_, err = NewStream(r).Read(make([]byte, 8))
+		chk.ErrIs(err, nwk.Err_Timeout)
+		w.Close()
+		_, err = NewStream(r).ReadByte()
+		chk.ErrIs(err, io.EOF)
+		chk.ErrIs(NewStream(struct{ ReadWriter }{r}).UnreadByte(), nwk.Err_NotSupported) // not a Peeker
+		r.Close()
+		chk.ShowPassFail(t, "Encoder & timeouts")
+	}
+}
+
+func Test_HalfClose(t *testing.T) {
+	tst.Testing("Conn accessors & half close", "", halfClose)
+
+	if halfClose {
+		chk.Reset()
+		l, err := NewListener(loopback, nil)
+		chk.Err(err, "Failed to create loopback listener", t.FailNow)
+		remotes := make(chan string, 1)
+		done := make(chan error, 1)
+		go func() {
+			done <- l.HandleARequest(func(cn int, serving string, rw ReadWriter) error {
+				remotes <- rw.(ConnInfo).RemoteAddr().String()
+				lines := 0
+				for { // read the request until the client closes its side
+					if _, err := rw.ReadString(); nil != err {
+						if io.EOF != err {
+							return err
+						}
+						break
+					}
+					lines++
+				}
+				return rw.WriteString(fmt.Sprintf("%d lines\n", lines))
+			})
+		}()
+
+		crw, err := NewClient(l.Addr(), time.Second, true)
+		chk.Err(err, "Failed to create client", t.FailNow)
+		ci, ok1 := crw.(ConnInfo)
+		hc, ok2 := crw.(HalfCloser)
+		if !chk.Tru(ok1 && ok2, "Client not a ConnInfo & HalfCloser") {
+			t.FailNow()
+		}
+		chk.Tru(l.Addr() == ci.RemoteAddr().String(), "RemoteAddr invalid")
+		_, ok := ci.Conn().(*net.TCPConn)
+		chk.Tru(ok, "Conn not a TCPConn")
+		crw.WriteString("one\ntwo\n")
+		crw.WriteString("three\n")
+		chk.Err(hc.CloseWrite(), "CloseWrite failed")
+		chk.Tru(ci.LocalAddr().String() == <-remotes, "Remote seen by the server invalid")
+		crw.ReadTimeout(time.Second)
+		r, err := crw.ReadString()
+		chk.Tru(nil == err && "3 lines\n" == r, "Response after CloseWrite invalid")
+		chk.Err(<-done, "Handler failed")
+		crw.Close()
+		l.Close()
+
+		w, pr := rwPair(true)
+		chk.ErrIs(w.CloseWrite(), nwk.Err_NotSupported)
+		chk.ErrIs(pr.CloseRead(), nwk.Err_NotSupported)
+		w.Close()
+		pr.Close()
+		chk.ShowPassFail(t, "Half close")
+	}
+}
+
+func Test_PeekSniff(t *testing.T) {
+	tst.Testing("Peek, Buffered, Discard & UnreadByte", "", peekSniff)
+
+	if peekSniff {
+		chk.Reset()
+		type bin struct {
+			Magic uint8
+			Val   uint32
+		}
+		w, r := rwPair(true)
+		done := pipeWrites(w,
+			func(w fullRW) error { return w.WriteString("GET one\n") },
+			func(w fullRW) error { return w.WriteStruct(binary.BigEndian, bin{0xB1, 42}) },
+			func(w fullRW) error { return w.WriteString("##GET two\n") })
+		texts, vals := []string{}, []uint32{}
+		for 3 > len(texts)+len(vals) {
+			b, err := r.Peek(1)
+			if nil != err {
+				chk.Err(err, "Peek failed")
+				break
+			}
+			switch {
+			case 0xB1 == b[0]:
+				v := bin{}
+				chk.Err(r.ReadStruct(binary.BigEndian, &v), "ReadStruct failed")
+				vals = append(vals, v.Val)
+			case '#' == b[0]:
+				n, err := r.Discard(2)
+				chk.Tru(nil == err && 2 == n, "Discard invalid")
+			default:
+				l, err := r.ReadString()
+				chk.Err(err, "ReadString failed")
+				texts = append(texts, l)
+			}
+		}
+		chk.Tru(2 == len(texts) && "GET one\n" == texts[0] && "GET two\n" == texts[1], "Text lines invalid")
+		chk.Tru(1 == len(vals) && 42 == vals[0], "Binary struct invalid")
+		chk.Err(<-done, "Write failed")
+
+		done = pipeWrites(w, func(w fullRW) error { return w.WriteString("xyz") })
+		b, err := r.ReadByte()
+		chk.Tru(nil == err && 'x' == b, "ReadByte invalid")
+		chk.Err(r.UnreadByte(), "UnreadByte failed")
+		chk.Tru(3 == r.Buffered(), "Buffered invalid")
+		p, err := r.Peek(3)
+		chk.Tru(nil == err && "xyz" == string(p), "Peek invalid")
+		chk.Err(<-done, "Write failed")
+		r.ReadTimeout(time.Millisecond * 50)
+		_, err = r.Peek(4)
+		chk.ErrIs(err, nwk.Err_Timeout)
+		w.Close()
+		r.Close()
+		chk.ShowPassFail(t, "Protocol sniffing")
+	}
+}
+
+// Check the matcher stops just past the first match, as bytes.Index
+func FuzzMarkerScan(f *testing.F) {
+	f.Add([]byte("aaab"), []byte("aab"))
+	f.Add([]byte("abababc"), []byte("ababc"))
+	f.Add([]byte("xyz"), []byte("q"))
+	f.Add([]byte("aaaaaaaaab"), []byte("aaaab"))
+	f.Fuzz(func(t *testing.T, data, pat []byte) {
+		if 0 == len(pat) {
+			return
+		}
+		kept := []byte{}
+		n, err := newMatcher(pat).scan(bytes.NewReader(data), 0, &kept)
+		i := bytes.Index(data, pat)
+		if -1 == i {
+			if io.EOF != err || len(data) != n {
+				t.Fatalf("no match: got %d %v", n, err)
+			}
+			return
+		}
+		if nil != err || i+len(pat) != n || !bytes.Equal(data[:n], kept) {
+			t.Fatalf("match at %d: got %d %v", i, n, err)
+		}
+		if 0 < n-1 {
+			if _, err = newMatcher(pat).scan(bytes.NewReader(data), n-1, nil); nwk.Err_ScanLimit != err {
+				t.Fatalf("limit %d: got %v", n-1, err)
+			}
+		}
+	})
+}
+
+// ------------------------------------------------------------------------- //
+
 func Test___fini(_ *testing.T) {
 	ticker.Stop()
 	xitSig <- true
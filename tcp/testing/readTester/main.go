@@ -29,7 +29,7 @@ var (
 
 func init() {
 	flag.BoolVar(&byReader, "r", false, "Use Reader and not ConnReader")
-	flag.StringVar(&ipport, "use", "127:7879", "Interface & port to use")
+	flag.StringVar(&ipport, "use", "127:7879", "Interface & port to use, port 0 picks a free port")
 }
 
 func pipeReader() {
@@ -93,6 +93,7 @@ func main() {
 	if dbg.ChkErr(err, "Failed to get listener: %v", err) {
 		return
 	}
+	dbg.Message("Listening on: %s", l.Addr())
 
 	defer l.Close()
 
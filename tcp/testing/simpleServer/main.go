@@ -31,7 +31,7 @@ var (
 )
 
 func init() {
-	flag.StringVar(&ipport, "use", "127:7879", "Interface & port to use")
+	flag.StringVar(&ipport, "use", "127:7879", "Interface & port to use, port 0 picks a free port")
 }
 
 func connHandler(cn int, serving string, rw tcp.ReadWriter) error {
@@ -96,6 +96,7 @@ func main() {
 
 	l, err := tcp.NewListener(ip, statPipe)
 	dbg.ChkErrX(err, "Failed to get listener: %v", err)
+	dbg.Message("Listening on: %s", l.Addr())
 
 	go listener(l)
 
@@ -0,0 +1,95 @@
+package tcp
+
+import (
+	"net"
+	"time"
+
+	"github.com/jayacarlson/nwk"
+)
+
+/*
+	Unix stream sockets, for local IPC using the same Listener, ConnHandler
+		and ReadWriter code as TCP
+
+		NewUnixListener( Path, Status chan ) ( *Listener, error ):
+			Same as NewListener, listening on the unix socket Path
+			A Path starting with '@' is in the abstract namespace
+			(linux), otherwise the socket file is created and is
+			removed by Listener.Close -- a socket file left by a
+			process that didn't close it gives nwk.Err_AddressInUse
+			The serving string / Remote is the peer address, usually
+			"@" as clients are unnamed
+
+		NewUnixEventListener( Path, Events chan ) ( *Listener, error ):
+			Same as NewUnixListener, but sends ListenerEvents
+
+		NewUnixClient( Path, timeout, buffered ) ( ReadWriter, error ):
+			Same as NewClient, connecting to the unix socket Path
+
+		PeerCred( ReadWriter ) ( Ucred, error ):
+			Returns the process, user and group IDs of the process at
+			the other end of a unix socket (SO_PEERCRED), e.g. called
+			by a ConnHandler to check who is connected
+			nwk.Err_NotSupported if not a unix socket or not on linux
+
+		ConnPeerCred( net.Conn ) ( Ucred, error ):
+			Same as PeerCred, for a net.Conn from WaitOnConnection
+*/
+
+type (
+	Ucred struct {
+		Pid int
+		Uid int
+		Gid int
+	}
+)
+
+// create a unix socket listener
+func NewUnixListener(path string, status chan<- string) (*Listener, error) {
+	return newUnixListener(path, status, nil)
+}
+
+// create a unix socket listener that reports its status as ListenerEvents
+func NewUnixEventListener(path string, events chan<- ListenerEvent) (*Listener, error) {
+	return newUnixListener(path, nil, events)
+}
+
+// create a client connected to a unix socket
+func NewUnixClient(path string, timeout time.Duration, buf bool) (ReadWriter, error) {
+	return newClient("unix", path, timeout, buf)
+}
+
+// ========================================================================= //
+
+// Return the credentials of the peer process of a unix socket ReadWriter
+func PeerCred(rw ReadWriter) (Ucred, error) {
+	ci, ok := rw.(ConnInfo)
+	if !ok {
+		return Ucred{}, nwk.Err_NotSupported
+	}
+	return ConnPeerCred(ci.Conn())
+}
+
+// Return the credentials of the peer process of a unix socket net.Conn
+func ConnPeerCred(conn net.Conn) (Ucred, error) {
+	uc, ok := UnwrapConn(conn).(*net.UnixConn)
+	if !ok {
+		return Ucred{}, nwk.Err_NotSupported
+	}
+	return peerCred(uc)
+}
+
+// ------------------------------------------------------------------------- //
+
+func newUnixListener(path string, status chan<- string, events chan<- ListenerEvent) (*Listener, error) {
+	l := Listener{stats: newListenStats(), adm: newAdmission(), hostIP: path, statPipe: status, evtPipe: events}
+
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if ListenDbg.ChkErr(err) {
+		return nil, nwk.ChkNetErr(err)
+	}
+	l.listener = listener
+	l.event(ListenerEvent{Kind: EvCreated})
+
+	return &l, nil
+}
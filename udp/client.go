@@ -0,0 +1,144 @@
+package udp
+
+import (
+	"encoding/binary"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/jayacarlson/dbg"
+	"github.com/jayacarlson/nwk"
+)
+
+var ClientDbg = dbg.Dbg{false, 0}
+
+/*
+	Simple UDP client; sends requests to a server and matches the replies
+
+		NewClient( srvrPort ) ( *Client, error ):
+			Create a new Client sending to the requested server, only
+			datagrams from that server are received
+			Defaults to a 1 second reply timeout and 2 retries
+
+		Client.SetRetry( timeout time.Duration, retries int ):
+			Set how long Request waits for each reply, and how many
+			times the request is sent again when none arrives
+
+		Client.Request( []byte ) ( []byte, error ):
+			Sends the request (with a new sequence ID) and returns
+			the matching reply, replies to earlier requests are
+			discarded -- nwk.Err_Timeout if no reply after the retries
+			Requests from several GO ROUTINES are sent one at a time
+
+		Client.Send( []byte ) error:
+			Sends a raw datagram (no sequence ID)
+
+		Client.Receive() ( []byte, error ):
+			Waits (up to the reply timeout, 0 for none) for a raw datagram
+
+		Client.Close() error:
+			Closes the client
+*/
+
+type (
+	Client struct {
+		mu      sync.Mutex // one Request at a time
+		seq     uint32     // sequence ID of the last request
+		timeout time.Duration
+		retries int
+		buf     []byte
+		conn    *net.UDPConn
+	}
+)
+
+func NewClient(srvrPort string) (*Client, error) {
+	udpa, err := net.ResolveUDPAddr("udp", srvrPort)
+	if ClientDbg.ChkErr(err) {
+		return nil, err
+	}
+	conn, err := net.DialUDP("udp", nil, udpa)
+	if err = nwk.ChkNetErr(err); nil != err {
+		ClientDbg.Error("DialUDP failed: %v", err)
+		return nil, err
+	}
+	return &Client{timeout: time.Second, retries: 2, buf: make([]byte, MaxPacket), conn: conn}, nil
+}
+
+// ========================================================================= //
+
+// Set the reply timeout and number of retries
+func (c *Client) SetRetry(timeout time.Duration, retries int) {
+	c.mu.Lock()
+	c.timeout, c.retries = timeout, retries
+	c.mu.Unlock()
+}
+
+// Send a request and return the matching reply
+func (c *Client) Request(req []byte) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seq++
+	pkt := make([]byte, seqLen, seqLen+len(req))
+	binary.BigEndian.PutUint32(pkt, c.seq)
+	pkt = append(pkt, req...)
+
+	for try := 0; try <= c.retries; try++ {
+		if _, err := c.conn.Write(pkt); nil != err {
+			return nil, nwk.ChkNetErr(err)
+		}
+		resp, err := c.reply()
+		if nwk.Err_Timeout == err {
+			ClientDbg.Warning("Request %d timed out, try %d", c.seq, try+1)
+			continue
+		}
+		return resp, err
+	}
+	return nil, nwk.Err_Timeout
+}
+
+// Send a raw datagram
+func (c *Client) Send(data []byte) error {
+	_, err := c.conn.Write(data)
+	return nwk.ChkNetErr(err)
+}
+
+// Wait for a raw datagram
+func (c *Client) Receive() ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setExpiry()
+	n, err := c.conn.Read(c.buf)
+	if err = nwk.ChkNetErr(err); nil != err {
+		return nil, err
+	}
+	return append([]byte(nil), c.buf[:n]...), nil
+}
+
+func (c *Client) Close() error {
+	return nwk.ChkNetErr(c.conn.Close())
+}
+
+// ------------------------------------------------------------------------- //
+
+// Read until the reply to the current request, must hold the lock
+func (c *Client) reply() ([]byte, error) {
+	c.setExpiry()
+	for {
+		n, err := c.conn.Read(c.buf)
+		if err = nwk.ChkNetErr(err); nil != err {
+			return nil, err
+		}
+		if seqLen > n || c.seq != binary.BigEndian.Uint32(c.buf) {
+			continue // late reply to an earlier request
+		}
+		return append([]byte(nil), c.buf[seqLen:n]...), nil
+	}
+}
+
+func (c *Client) setExpiry() {
+	expiry := time.Time{}
+	if 0 != c.timeout {
+		expiry = time.Now().Add(c.timeout)
+	}
+	c.conn.SetReadDeadline(expiry)
+}
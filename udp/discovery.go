@@ -0,0 +1,192 @@
+package udp
+
+import (
+	"bytes"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/jayacarlson/nwk"
+)
+
+/*
+	Simple service announcement / discovery over a multicast group
+
+		NewAnnouncer( Group, Lead, Name, Info ) ( *Announcer, error ):
+			Creates an Announcer for the named service, joined to the
+			group on the interface matching Lead (see multicast.go)
+			Info is any data for those discovering the service, e.g.
+			the ip:port it is served on
+
+		Announcer.Serve() error:
+			Answers discovery queries for the service (or for any
+			service) until the Announcer is closed
+			Can be used as a GO ROUTINE or not
+
+		Announcer.Announce() error:
+			Sends an unsolicited announcement to the group, e.g. on
+			startup or when Info changes
+
+		Announcer.SetInfo( []byte ):
+			Changes the Info sent in announcements
+
+		Announcer.Close():
+			Closes the Announcer, Serve exits with nwk.Err_NoConnection
+
+		Discover( Group, Lead, Name, wait time.Duration ) ( []Service, error ):
+			Sends a query for the named service ("" for all services)
+			to the group and returns the Services that answer within
+			wait, each answering address & name reported once
+
+		ParseAnnouncement( []byte, *net.UDPAddr ) ( Service, bool ):
+			Returns the Service from an announcement packet, e.g. to
+			watch for announcements using a MulticastListener, false
+			if the data is not an announcement
+
+	Packets start with "NWKD", a kind byte (query / announcement), the
+		length of the service name and the name, announcements then have
+		the Info as the rest of the packet
+*/
+
+type (
+	Service struct {
+		Name string
+		Info []byte
+		From *net.UDPAddr // where the announcement came from
+	}
+
+	Announcer struct {
+		mu   sync.Mutex
+		name string
+		info []byte
+		l    *Listener
+		s    *MulticastSender
+	}
+)
+
+const (
+	discQuery    byte = 1
+	discAnnounce byte = 2
+)
+
+var discMagic = []byte("NWKD")
+
+// create an announcer for the named service
+func NewAnnouncer(group, lead, name string, info []byte) (*Announcer, error) {
+	if 255 < len(name) {
+		return nil, nwk.Err_IllegalParam
+	}
+	l, err := NewMulticastListener(group, lead, nil)
+	if nil != err {
+		return nil, err
+	}
+	s, err := NewMulticastSender(group, lead)
+	if nil != err {
+		l.Close()
+		return nil, err
+	}
+	return &Announcer{name: name, info: info, l: l, s: s}, nil
+}
+
+// Discover the named service, "" for all
+func Discover(group, lead, name string, wait time.Duration) ([]Service, error) {
+	if 255 < len(name) {
+		return nil, nwk.Err_IllegalParam
+	}
+	s, err := NewMulticastSender(group, lead)
+	if nil != err {
+		return nil, err
+	}
+	defer s.Close()
+	if err = s.Send(discPacket(discQuery, name, nil)); nil != err {
+		return nil, err
+	}
+
+	found := []Service{}
+	seen := map[string]bool{}
+	end := time.Now().Add(wait)
+	for left := wait; 0 < left; left = time.Until(end) {
+		data, from, err := s.ReceiveFrom(left)
+		if nwk.Err_Timeout == err {
+			break
+		}
+		if nil != err {
+			return found, err
+		}
+		svc, ok := ParseAnnouncement(data, from)
+		if !ok || ("" != name && name != svc.Name) || seen[from.String()+" "+svc.Name] {
+			continue
+		}
+		seen[from.String()+" "+svc.Name] = true
+		found = append(found, svc)
+	}
+	return found, nil
+}
+
+// Return the Service from an announcement packet
+func ParseAnnouncement(data []byte, from *net.UDPAddr) (Service, bool) {
+	kind, name, info, ok := parseDisc(data)
+	if !ok || discAnnounce != kind {
+		return Service{}, false
+	}
+	return Service{Name: name, Info: info, From: from}, true
+}
+
+// ========================================================================= //
+
+// Answer discovery queries until closed
+func (a *Announcer) Serve() error {
+	return a.l.HandlePackets(func(pn int, from *net.UDPAddr, data []byte, l *Listener) error {
+		kind, name, _, ok := parseDisc(data)
+		if !ok || discQuery != kind || ("" != name && a.name != name) {
+			return nil
+		}
+		_, err := a.s.conn.WriteToUDP(a.packet(), from) // from the sender, each announcer has its own port
+		return nwk.ChkNetErr(err)
+	}, nil)
+}
+
+// Send an announcement to the group
+func (a *Announcer) Announce() error {
+	return a.s.Send(a.packet())
+}
+
+// Change the announced info
+func (a *Announcer) SetInfo(info []byte) {
+	a.mu.Lock()
+	a.info = info
+	a.mu.Unlock()
+}
+
+func (a *Announcer) Close() {
+	a.s.Close()
+	a.l.Close()
+}
+
+// ------------------------------------------------------------------------- //
+
+func (a *Announcer) packet() []byte {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return discPacket(discAnnounce, a.name, a.info)
+}
+
+func discPacket(kind byte, name string, info []byte) []byte {
+	pkt := make([]byte, 0, len(discMagic)+2+len(name)+len(info))
+	pkt = append(pkt, discMagic...)
+	pkt = append(pkt, kind, byte(len(name)))
+	pkt = append(pkt, name...)
+	return append(pkt, info...)
+}
+
+func parseDisc(data []byte) (kind byte, name string, info []byte, ok bool) {
+	hl := len(discMagic) + 2
+	if hl > len(data) || !bytes.Equal(discMagic, data[:len(discMagic)]) {
+		return 0, "", nil, false
+	}
+	kind, nl := data[hl-2], int(data[hl-1])
+	if hl+nl > len(data) {
+		return 0, "", nil, false
+	}
+	return kind, string(data[hl : hl+nl]), data[hl+nl:], true
+}
@@ -0,0 +1,171 @@
+package udp
+
+import (
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/jayacarlson/dbg"
+	"github.com/jayacarlson/nwk"
+)
+
+var ListenDbg = dbg.Dbg{false, 0}
+
+/*
+	UDP server side, receives datagrams and passes them to a handler
+
+		NewListener( ListenIP, Status chan ) ( *Listener, error ):
+			Creates a UDP socket on the local ip:port (port 0 picks
+			a free port, see Addr)
+				Status:   Channel to receive status messages:
+							"Listener Created" on startup
+							"Listener Closed" finally sent on close
+
+		Listener.SetTimeout( time.Duration ):
+			HandlePackets / HandleRequests exit with nwk.Err_Timeout if
+			no packet arrives within the duration, 0 is no timeout
+
+		Listener.Addr() string:
+			Returns the address the listener is bound to
+
+		Listener.Close():
+			Close the listener, any HandlePackets / HandleRequests
+			exits with nwk.Err_NoConnection
+
+		Listener.Counts() ( packets, errors int ):
+			Returns the number of packets received and the number
+			of errors returned by the handlers
+
+		Listener.WriteTo( []byte, *net.UDPAddr ) error:
+			Sends a datagram from the listener's address
+
+		Listener.HandlePackets( PacketHandler, ErrPipe ) error:
+			Reads datagrams until an error (e.g. closing the Listener)
+			Can be used as a GO ROUTINE or not
+			Spawns a new GO ROUTINE for each packet with the
+			PacketHandler func
+			Any error from the PacketHandler is sent through any
+			supplied ErrPipe
+
+		Listener.HandleRequests( RequestHandler, ErrPipe ) error:
+			Same as HandlePackets for requests from a Client, the
+			reply returned by the RequestHandler is sent back with
+			the sequence ID of the request
+			Packets too short to hold a sequence ID are dropped
+*/
+
+type (
+	PacketHandler  func(packetNumber int, from *net.UDPAddr, data []byte, l *Listener) error
+	RequestHandler func(requestNumber int, from *net.UDPAddr, req []byte) ([]byte, error)
+
+	Listener struct {
+		packets  uint32        // total number of packets received
+		errors   uint32        // handler errors
+		statPipe chan<- string // chan for any status output
+		timeout  time.Duration // read timeout for the Handle funcs
+		conn     *net.UDPConn
+	}
+)
+
+// create a UDP listener, receiving datagrams from remote (client) PCs
+func NewListener(ipPort string, status chan<- string) (*Listener, error) {
+	l := Listener{statPipe: status}
+
+	udpa, err := net.ResolveUDPAddr("udp", ipPort)
+	if ListenDbg.ChkErr(err) {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp", udpa)
+	if ListenDbg.ChkErr(err) {
+		return nil, nwk.ChkNetErr(err)
+	}
+	l.conn = conn
+	l.status("Listener Created")
+
+	return &l, nil
+}
+
+// ========================================================================= //
+
+// Set the read timeout for the Handle funcs
+func (l *Listener) SetTimeout(timeout time.Duration) {
+	l.timeout = timeout
+}
+
+// Return the actual address the listener is bound to
+func (l *Listener) Addr() string {
+	return l.conn.LocalAddr().String()
+}
+
+// Close the listener
+func (l *Listener) Close() {
+	l.conn.Close()
+	l.status("Listener Closed")
+}
+
+// Return the number of packets received and handler errors
+func (l *Listener) Counts() (packets, errors int) {
+	return int(atomic.LoadUint32(&l.packets)), int(atomic.LoadUint32(&l.errors))
+}
+
+// Send a datagram to the addr
+func (l *Listener) WriteTo(data []byte, addr *net.UDPAddr) error {
+	_, err := l.conn.WriteToUDP(data, addr)
+	return nwk.ChkNetErr(err)
+}
+
+// Handle datagrams until an error
+func (l *Listener) HandlePackets(ph PacketHandler, errPipe chan<- error) error {
+	return l.handle(func(num int, from *net.UDPAddr, data []byte) error {
+		return ph(num, from, data, l)
+	}, errPipe)
+}
+
+// Handle requests from Clients until an error
+func (l *Listener) HandleRequests(rh RequestHandler, errPipe chan<- error) error {
+	return l.handle(func(num int, from *net.UDPAddr, data []byte) error {
+		if seqLen > len(data) {
+			ListenDbg.Warning("Short request from %v dropped", from)
+			return nil
+		}
+		resp, err := rh(num, from, data[seqLen:])
+		if nil != err || nil == resp {
+			return err
+		}
+		return l.WriteTo(append(data[:seqLen:seqLen], resp...), from)
+	}, errPipe)
+}
+
+// ------------------------------------------------------------------------- //
+
+func (l *Listener) handle(fn func(int, *net.UDPAddr, []byte) error, errPipe chan<- error) error {
+	buf := make([]byte, MaxPacket)
+	for {
+		expiry := time.Time{}
+		if 0 != l.timeout {
+			expiry = time.Now().Add(l.timeout)
+		}
+		l.conn.SetReadDeadline(expiry)
+		n, from, err := l.conn.ReadFromUDP(buf)
+		if err = nwk.ChkNetErr(err); nil != err {
+			return err
+		}
+		num := int(atomic.AddUint32(&l.packets, 1))
+		data := append([]byte(nil), buf[:n]...)
+		go func() {
+			err := fn(num, from, data)
+			if nil != err {
+				atomic.AddUint32(&l.errors, 1)
+				if nil != errPipe {
+					errPipe <- err
+				}
+			}
+		}()
+	}
+}
+
+func (l *Listener) status(s string) {
+	if nil != l.statPipe {
+		l.statPipe <- s
+	}
+}
@@ -0,0 +1,176 @@
+package udp
+
+import (
+	"net"
+	"time"
+
+	"github.com/jayacarlson/nwk"
+)
+
+/*
+	Multicast send / receive on an interface picked the same way as
+		nwk.FindMyIP4Addr, i.e. the lead of its IP4 address:
+			"127"		the local loopback
+			"192.168"	the first interface with a "192.168" address
+			""			the 1st non-loopback interface
+
+		NewMulticastListener( Group, Lead, Status chan ) ( *Listener, error ):
+			Creates a Listener that joins the group ("239.1.2.3:5000")
+			on the interface, use HandlePackets to receive -- several
+			listeners (processes) can join the same group and port
+			The status messages are the same as for NewListener
+
+		NewMulticastSender( Group, Lead ) ( *MulticastSender, error ):
+			Creates a socket sending to the group from the interface,
+			defaults to a TTL of 1 (the local network) with loopback
+			on, so listeners on this host also get the packets
+
+		MulticastSender.SetTTL( int ) error:
+			Set the number of routers the packets may cross, 0 keeps
+			them on this host -- 0 to 255, else nwk.Err_IllegalParam
+
+		MulticastSender.SetLoopback( bool ) error:
+			Set whether this host also receives the packets sent
+
+		MulticastSender.Send( []byte ) error:
+			Sends a datagram to the group
+
+		MulticastSender.ReceiveFrom( timeout ) ( []byte, *net.UDPAddr, error ):
+			Waits (0 for no timeout) for a datagram sent directly to
+			the sender, e.g. a unicast reply to a multicast query
+
+		MulticastSender.Addr() string:
+			Returns the address the sender is bound to
+
+		MulticastSender.Close() error:
+			Closes the sender
+
+		Only supported on unix-like systems, elsewhere the sender
+		returns nwk.Err_NotSupported
+*/
+
+type (
+	MulticastSender struct {
+		group *net.UDPAddr
+		buf   []byte
+		conn  *net.UDPConn
+	}
+)
+
+// create a listener joined to the multicast group on the interface matching lead
+func NewMulticastListener(group, lead string, status chan<- string) (*Listener, error) {
+	gaddr, inf, _, err := multicastAddrs(group, lead)
+	if ListenDbg.ChkErr(err) {
+		return nil, err
+	}
+	conn, err := net.ListenMulticastUDP("udp4", inf, gaddr)
+	if ListenDbg.ChkErr(err) {
+		return nil, nwk.ChkNetErr(err)
+	}
+	l := Listener{statPipe: status, conn: conn}
+	l.status("Listener Created")
+
+	return &l, nil
+}
+
+// create a sender to the multicast group from the interface matching lead
+func NewMulticastSender(group, lead string) (*MulticastSender, error) {
+	gaddr, _, ip, err := multicastAddrs(group, lead)
+	if ClientDbg.ChkErr(err) {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: ip})
+	if ClientDbg.ChkErr(err) {
+		return nil, nwk.ChkNetErr(err)
+	}
+	s := MulticastSender{group: gaddr, buf: make([]byte, MaxPacket), conn: conn}
+	err = s.control(func(fd uintptr) error { return setMulticastIF(fd, ip) })
+	if nil == err {
+		err = s.SetTTL(1)
+	}
+	if nil == err {
+		err = s.SetLoopback(true)
+	}
+	if nil != err {
+		conn.Close()
+		return nil, err
+	}
+	return &s, nil
+}
+
+// ========================================================================= //
+
+// Set the multicast TTL
+func (s *MulticastSender) SetTTL(ttl int) error {
+	if 0 > ttl || 255 < ttl {
+		return nwk.Err_IllegalParam
+	}
+	return s.control(func(fd uintptr) error { return setMulticastTTL(fd, ttl) })
+}
+
+// Set whether this host receives the packets sent
+func (s *MulticastSender) SetLoopback(on bool) error {
+	return s.control(func(fd uintptr) error { return setMulticastLoop(fd, on) })
+}
+
+// Send a datagram to the group
+func (s *MulticastSender) Send(data []byte) error {
+	_, err := s.conn.WriteToUDP(data, s.group)
+	return nwk.ChkNetErr(err)
+}
+
+// Wait for a datagram sent to the sender
+func (s *MulticastSender) ReceiveFrom(timeout time.Duration) ([]byte, *net.UDPAddr, error) {
+	expiry := time.Time{}
+	if 0 != timeout {
+		expiry = time.Now().Add(timeout)
+	}
+	s.conn.SetReadDeadline(expiry)
+	n, from, err := s.conn.ReadFromUDP(s.buf)
+	if err = nwk.ChkNetErr(err); nil != err {
+		return nil, nil, err
+	}
+	return append([]byte(nil), s.buf[:n]...), from, nil
+}
+
+// Return the actual address the sender is bound to
+func (s *MulticastSender) Addr() string {
+	return s.conn.LocalAddr().String()
+}
+
+func (s *MulticastSender) Close() error {
+	return nwk.ChkNetErr(s.conn.Close())
+}
+
+// ------------------------------------------------------------------------- //
+
+// Resolve the group & find the interface, the group must be multicast
+func multicastAddrs(group, lead string) (*net.UDPAddr, *net.Interface, net.IP, error) {
+	gaddr, err := net.ResolveUDPAddr("udp4", group)
+	if nil != err {
+		return nil, nil, nil, err
+	}
+	if !gaddr.IP.IsMulticast() {
+		return nil, nil, nil, nwk.Err_IllegalParam
+	}
+	inf, ip, err := nwk.FindMyIP4Interface(lead)
+	if nil != err {
+		return nil, nil, nil, err
+	}
+	return gaddr, inf, net.ParseIP(ip).To4(), nil
+}
+
+func (s *MulticastSender) control(fn func(fd uintptr) error) error {
+	rc, err := s.conn.SyscallConn()
+	if nil != err {
+		return nwk.ChkNetErr(err)
+	}
+	var serr error
+	err = rc.Control(func(fd uintptr) {
+		serr = fn(fd)
+	})
+	if nil == err {
+		err = serr
+	}
+	return err
+}
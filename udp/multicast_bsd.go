@@ -0,0 +1,22 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+// +build darwin dragonfly freebsd netbsd openbsd
+
+package udp
+
+import (
+	"os"
+	"syscall"
+)
+
+// darwin and the BSDs take a u_char for the multicast TTL and loop options
+func setMulticastTTL(fd uintptr, ttl int) error {
+	return os.NewSyscallError("setsockopt IP_MULTICAST_TTL", syscall.SetsockoptByte(int(fd), syscall.IPPROTO_IP, syscall.IP_MULTICAST_TTL, byte(ttl)))
+}
+
+func setMulticastLoop(fd uintptr, on bool) error {
+	v := byte(0)
+	if on {
+		v = 1
+	}
+	return os.NewSyscallError("setsockopt IP_MULTICAST_LOOP", syscall.SetsockoptByte(int(fd), syscall.IPPROTO_IP, syscall.IP_MULTICAST_LOOP, v))
+}
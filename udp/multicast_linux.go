@@ -0,0 +1,22 @@
+//go:build linux
+// +build linux
+
+package udp
+
+import (
+	"os"
+	"syscall"
+)
+
+// linux takes an int for the multicast TTL and loop options
+func setMulticastTTL(fd uintptr, ttl int) error {
+	return os.NewSyscallError("setsockopt IP_MULTICAST_TTL", syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_MULTICAST_TTL, ttl))
+}
+
+func setMulticastLoop(fd uintptr, on bool) error {
+	v := 0
+	if on {
+		v = 1
+	}
+	return os.NewSyscallError("setsockopt IP_MULTICAST_LOOP", syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_MULTICAST_LOOP, v))
+}
@@ -0,0 +1,22 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd
+
+package udp
+
+import (
+	"net"
+
+	"github.com/jayacarlson/nwk"
+)
+
+func setMulticastIF(fd uintptr, ip net.IP) error {
+	return nwk.Err_NotSupported
+}
+
+func setMulticastTTL(fd uintptr, ttl int) error {
+	return nwk.Err_NotSupported
+}
+
+func setMulticastLoop(fd uintptr, on bool) error {
+	return nwk.Err_NotSupported
+}
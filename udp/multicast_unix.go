@@ -0,0 +1,16 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+// +build linux darwin dragonfly freebsd netbsd openbsd
+
+package udp
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+func setMulticastIF(fd uintptr, ip net.IP) error {
+	var a [4]byte
+	copy(a[:], ip.To4())
+	return os.NewSyscallError("setsockopt IP_MULTICAST_IF", syscall.SetsockoptInet4Addr(int(fd), syscall.IPPROTO_IP, syscall.IP_MULTICAST_IF, a))
+}
@@ -0,0 +1,86 @@
+package udp
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/jayacarlson/nwk"
+)
+
+/*
+	Some simple routines to handle UDP communications, mirroring the tcp
+		package:
+
+		Creating a simple server...
+			l = NewListener()
+			go l.HandlePackets(...)		// raw datagrams
+			  -- or --
+			go l.HandleRequests(...)	// requests from a Client, with replies
+			l.Close()
+
+		Creating a simple client...
+			c = NewClient(ipaddr)
+			resp = c.Request(req)		// sent & retried until the reply arrives
+			  -- or --
+			c.Send(data) / c.Receive()	// raw datagrams
+			c.Close()
+
+		Multicast groups and service discovery are in multicast.go and
+		discovery.go
+
+
+	type PacketHandler( int, *net.UDPAddr, []byte, *Listener ) error:
+		Called for each datagram received by HandlePackets with:
+			packet number (ref only)
+			address the packet came from
+			the packet data
+			the Listener, to send any reply with Listener.WriteTo
+
+	type RequestHandler( int, *net.UDPAddr, []byte ) ( []byte, error ):
+		Called for each request received by HandleRequests with:
+			request number (ref only)
+			address the request came from
+			the request data
+		Returns the reply sent back to the Client, nil for no reply
+
+	Requests and replies start with a 4 byte (big endian) sequence ID
+		added by the Client and HandleRequests, so a reply is matched to
+		its request and late replies to an earlier (retried) request
+		are discarded -- the handlers only see the data
+
+	EncodeStruct( binary.ByteOrder, interface{} ) ( []byte, error ):
+		Encodes the interface in the given ByteOrder, as with the tcp
+		ReadWriter.WriteStruct
+
+	DecodeStruct( binary.ByteOrder, []byte, interface{} ) error:
+		Fills the interface from data in the given ByteOrder, as with
+		the tcp ReadWriter.ReadStruct -- nwk.Err_BadData if the data
+		is too short, any extra data is ignored
+*/
+
+const (
+	MaxPacket = 65507 // largest UDP payload (over IPv4)
+	seqLen    = 4     // sequence ID header on requests / replies
+)
+
+// Encode a struct in the given byte order
+func EncodeStruct(ord binary.ByteOrder, i interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	err := binary.Write(buf, ord, i)
+	if nil != err {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode a struct from data in the given byte order
+func DecodeStruct(ord binary.ByteOrder, data []byte, i interface{}) error {
+	bsz := binary.Size(i)
+	if 0 > bsz {
+		return nwk.Err_IllegalParam
+	}
+	if len(data) < bsz {
+		return nwk.Err_BadData
+	}
+	return binary.Read(bytes.NewReader(data[:bsz]), ord, i)
+}
@@ -0,0 +1,274 @@
+package udp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jayacarlson/nwk"
+	"github.com/jayacarlson/tst"
+)
+
+var (
+	chk = tst.Chk{}
+
+	loopback = "127.0.0.1:0" // ephemeral port, clients connect to l.Addr()
+
+	enableAll = true
+
+	packetTests   = (enableAll || true)
+	requestTests  = (enableAll || false)
+	requestRetry  = (enableAll || false)
+	structHelpers = (enableAll || false)
+	multicast     = (enableAll || false)
+	discovery     = (enableAll || false)
+
+	mcGroup = "239.77.68.1:0" // port set by mcPort
+	mcLead  = "127"           // multicast over the loopback
+)
+
+type testRec struct {
+	ID    uint16
+	Temp  int32
+	Flags [4]byte
+}
+
+func Test_Packets(t *testing.T) {
+	tst.Testing("Raw datagrams", "", packetTests)
+
+	if packetTests {
+		chk.Reset()
+		l, err := NewListener(loopback, nil)
+		chk.Err(err, "Failed to create listener", t.FailNow)
+		errs := make(chan error, 1)
+		done := make(chan error)
+		go func() {
+			done <- l.HandlePackets(func(pn int, from *net.UDPAddr, data []byte, l *Listener) error {
+				if "bad" == string(data) {
+					return nwk.Err_BadData
+				}
+				return l.WriteTo([]byte(from.String()+" "+string(data)), from)
+			}, errs)
+		}()
+
+		c, err := NewClient(l.Addr())
+		chk.Err(err, "Failed to create client", t.FailNow)
+		chk.Err(c.Send([]byte("hello")), "Send failed")
+		r, err := c.Receive()
+		chk.Err(err, "Receive failed")
+		chk.Tru(c.conn.LocalAddr().String()+" hello" == string(r), "Source address / data invalid")
+		chk.Err(c.Send([]byte("bad")), "Send failed")
+		chk.ErrIs(<-errs, nwk.Err_BadData)
+		p, e := l.Counts()
+		chk.Tru(2 == p && 1 == e, "Counts invalid")
+		c.Close()
+		l.Close()
+		chk.ErrIs(<-done, nwk.Err_NoConnection)
+		chk.ShowPassFail(t, "HandlePackets / Send / Receive")
+
+		chk.Reset()
+		l, err = NewListener(loopback, nil)
+		chk.Err(err, "Failed to create listener", t.FailNow)
+		l.SetTimeout(time.Millisecond * 50)
+		chk.ErrIs(l.HandlePackets(nil, nil), nwk.Err_Timeout)
+		l.Close()
+		chk.ShowPassFail(t, "Listener timeout")
+	}
+}
+
+func Test_Requests(t *testing.T) {
+	tst.Testing("Request / reply", "", requestTests)
+
+	if requestTests {
+		chk.Reset()
+		l, err := NewListener(loopback, nil)
+		chk.Err(err, "Failed to create listener", t.FailNow)
+		go l.HandleRequests(func(rn int, from *net.UDPAddr, req []byte) ([]byte, error) {
+			if "quiet" == string(req) {
+				return nil, nil
+			}
+			return []byte(strings.ToUpper(string(req))), nil
+		}, nil)
+
+		c, err := NewClient(l.Addr())
+		chk.Err(err, "Failed to create client", t.FailNow)
+		for _, s := range []string{"one", "two", "three"} {
+			r, err := c.Request([]byte(s))
+			chk.Err(err, "Request failed")
+			chk.Tru(strings.ToUpper(s) == string(r), "Reply invalid")
+		}
+		c.SetRetry(time.Millisecond*50, 1)
+		_, err = c.Request([]byte("quiet"))
+		chk.ErrIs(err, nwk.Err_Timeout)
+		c.Close()
+		l.Close()
+		chk.ShowPassFail(t, "Request / reply matching")
+	}
+}
+
+func Test_RequestRetry(t *testing.T) {
+	tst.Testing("Request retries", "", requestRetry)
+
+	if requestRetry {
+		chk.Reset()
+		l, err := NewListener(loopback, nil)
+		chk.Err(err, "Failed to create listener", t.FailNow)
+		var calls int32
+		go l.HandleRequests(func(rn int, from *net.UDPAddr, req []byte) ([]byte, error) {
+			switch atomic.AddInt32(&calls, 1) {
+			case 1:
+				return nil, nil // dropped, the client retries
+			case 3:
+				time.Sleep(time.Millisecond * 150) // reply arrives after the retry
+			}
+			return req, nil
+		}, nil)
+
+		c, err := NewClient(l.Addr())
+		chk.Err(err, "Failed to create client", t.FailNow)
+		c.SetRetry(time.Millisecond*100, 2)
+		r, err := c.Request([]byte("first"))
+		chk.Err(err, "Request after a retry failed")
+		chk.Tru("first" == string(r), "Retried reply invalid")
+		chk.Tru(2 == atomic.LoadInt32(&calls), "Retry count invalid")
+
+		r, err = c.Request([]byte("second")) // 1st try is slow, 2nd answered
+		chk.Err(err, "Request failed")
+		chk.Tru("second" == string(r), "Reply invalid")
+		time.Sleep(time.Millisecond * 100) // let the late reply arrive
+		r, err = c.Request([]byte("third"))
+		chk.Err(err, "Request failed")
+		chk.Tru("third" == string(r), "Late reply not discarded")
+		c.Close()
+		l.Close()
+		chk.ShowPassFail(t, "Retries and late replies")
+	}
+}
+
+func Test_StructHelpers(t *testing.T) {
+	tst.Testing("Struct encode / decode", "", structHelpers)
+
+	if structHelpers {
+		chk.Reset()
+		in := testRec{ID: 0x1234, Temp: -40, Flags: [4]byte{1, 2, 3, 4}}
+		for _, ord := range []binary.ByteOrder{binary.BigEndian, binary.LittleEndian} {
+			data, err := EncodeStruct(ord, &in)
+			chk.Err(err, "EncodeStruct failed")
+			chk.Tru(10 == len(data), "Encoded size invalid")
+			out := testRec{}
+			chk.Err(DecodeStruct(ord, data, &out), "DecodeStruct failed")
+			chk.Tru(in == out, "Decoded struct invalid")
+		}
+		data, _ := EncodeStruct(binary.BigEndian, &in)
+		chk.Tru(bytes.Equal([]byte{0x12, 0x34}, data[:2]), "Byte order invalid")
+		chk.ErrIs(DecodeStruct(binary.BigEndian, data[:9], &testRec{}), nwk.Err_BadData)
+		chk.ShowPassFail(t, "EncodeStruct / DecodeStruct")
+	}
+}
+
+// Return the group with a free port, the same port is used for the group
+func mcPort(t *testing.T) string {
+	l, err := NewListener("127.0.0.1:0", nil)
+	chk.Err(err, "Failed to find a free port", t.FailNow)
+	defer l.Close()
+	return strings.Replace(mcGroup, ":0", l.Addr()[strings.LastIndex(l.Addr(), ":"):], 1)
+}
+
+func Test_Multicast(t *testing.T) {
+	tst.Testing("Multicast send / receive", "", multicast)
+
+	if multicast {
+		chk.Reset()
+		group := mcPort(t)
+		got := make(chan string, 4)
+		ls := []*Listener{}
+		for i := 0; i < 2; i++ { // 2 listeners on the same group & port
+			l, err := NewMulticastListener(group, mcLead, nil)
+			chk.Err(err, "Failed to join group", t.FailNow)
+			go l.HandlePackets(func(pn int, from *net.UDPAddr, data []byte, l *Listener) error {
+				got <- string(data)
+				return nil
+			}, nil)
+			ls = append(ls, l)
+		}
+		s, err := NewMulticastSender(group, mcLead)
+		chk.Err(err, "Failed to create sender", t.FailNow)
+		chk.Err(s.SetTTL(0), "SetTTL failed")
+		chk.ErrIs(s.SetTTL(256), nwk.Err_IllegalParam)
+		chk.Err(s.Send([]byte("to all")), "Send failed")
+		for i := 0; i < 2; i++ {
+			select {
+			case r := <-got:
+				chk.Tru("to all" == r, "Multicast data invalid")
+			case <-time.After(time.Second):
+				chk.Tru(false, "Multicast packet not received")
+			}
+		}
+		chk.Err(s.SetLoopback(false), "SetLoopback failed")
+		s.Close()
+		for _, l := range ls {
+			l.Close()
+		}
+		_, err = NewMulticastSender("10.1.2.3:5000", mcLead)
+		chk.ErrIs(err, nwk.Err_IllegalParam)
+		chk.ShowPassFail(t, "Join, send & receive")
+	}
+}
+
+func Test_Discovery(t *testing.T) {
+	tst.Testing("Service announcement / discovery", "", discovery)
+
+	if discovery {
+		chk.Reset()
+		group := mcPort(t)
+		as := []*Announcer{}
+		for _, svc := range []string{"printer", "sensor", "sensor"} {
+			a, err := NewAnnouncer(group, mcLead, svc, []byte(svc+"-info"))
+			chk.Err(err, "Failed to create announcer", t.FailNow)
+			go a.Serve()
+			as = append(as, a)
+		}
+		as[2].SetInfo([]byte("sensor-2"))
+
+		found, err := Discover(group, mcLead, "sensor", time.Millisecond*200)
+		chk.Err(err, "Discover failed")
+		chk.Tru(2 == len(found), "Sensors not all found")
+		for _, svc := range found {
+			chk.Tru("sensor" == svc.Name && nil != svc.From, "Service invalid")
+			chk.Tru("sensor-info" == string(svc.Info) || "sensor-2" == string(svc.Info), "Service info invalid")
+		}
+		found, err = Discover(group, mcLead, "", time.Millisecond*200)
+		chk.Err(err, "Discover failed")
+		chk.Tru(3 == len(found), "Services not all found")
+		chk.ShowPassFail(t, "Discover")
+
+		chk.Reset()
+		watch, err := NewMulticastListener(group, mcLead, nil)
+		chk.Err(err, "Failed to join group", t.FailNow)
+		seen := make(chan Service, 1)
+		go watch.HandlePackets(func(pn int, from *net.UDPAddr, data []byte, l *Listener) error {
+			if svc, ok := ParseAnnouncement(data, from); ok {
+				seen <- svc
+			}
+			return nil
+		}, nil)
+		chk.Err(as[0].Announce(), "Announce failed")
+		select {
+		case svc := <-seen:
+			chk.Tru("printer" == svc.Name && "printer-info" == string(svc.Info), "Announcement invalid")
+		case <-time.After(time.Second):
+			chk.Tru(false, "Announcement not received")
+		}
+		_, ok := ParseAnnouncement([]byte("NWKD\x02\x09short"), nil)
+		chk.Tru(!ok, "Bad announcement accepted")
+		watch.Close()
+		for _, a := range as {
+			a.Close()
+		}
+		chk.ShowPassFail(t, "Announce / ParseAnnouncement")
+	}
+}